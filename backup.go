@@ -14,6 +14,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -30,7 +31,16 @@ type Backups struct {
 }
 
 func (b *Backups) Less(i, j int) bool {
-	return (*b).bs[i].ts < ((*b).bs[j].ts)
+	return (*b).bs[i].less((*b).bs[j])
+}
+
+// less compares create time of backups,
+// ts is in second, so the file name (with millisecond) is compared when ts are equal.
+func (b Backup) less(o Backup) bool {
+	if b.ts != o.ts {
+		return b.ts < o.ts
+	}
+	return b.fp < o.fp
 }
 
 func (b *Backups) Swap(i, j int) {
@@ -67,10 +77,46 @@ func listBackups(outputPath string, max int) (*Backups, error) {
 	return b, nil
 }
 
+// scanBackups lists all backup log files without removing.
+func scanBackups(outputPath string) (*Backups, error) {
+	b := new(Backups)
+	err := b.scan(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// sorted returns backups sorted from oldest to newest.
+func (b *Backups) sorted() []Backup {
+	bs := make([]Backup, len(b.bs))
+	copy(bs, b.bs)
+	sort.Slice(bs, func(i, j int) bool {
+		return bs[i].less(bs[j])
+	})
+	return bs
+}
+
 // List all backup log files (in init process),
 // and remove them if there are too many backups.
 func (b *Backups) list(outputPath string, max int) error {
 
+	err := b.scan(outputPath)
+	if err != nil {
+		return err
+	}
+
+	for b.Len() > max {
+		v := heap.Pop(b)
+		removeBackup(v.(Backup).fp)
+	}
+
+	return nil
+}
+
+// scan pushes all backup log files into b.
+func (b *Backups) scan(outputPath string) error {
+
 	dir := filepath.Dir(outputPath)
 	ns, err := ioutil.ReadDir(dir)
 	if err != nil {
@@ -89,11 +135,6 @@ func (b *Backups) list(outputPath string, max int) error {
 		}
 	}
 
-	for b.Len() > max {
-		v := heap.Pop(b)
-		os.Remove(v.(Backup).fp)
-	}
-
 	return nil
 }
 
@@ -107,12 +148,29 @@ func getPrefixAndExt(outputPath string) (prefix, ext string) {
 
 const backupTimeFmt = "2006-01-02T15:04:05.000Z0700"
 
+// compressExt is the extension appended to compressed backups.
+// e.g. a-time.log -> a-time.log.gz
+const compressExt = ".gz"
+
+func isCompressed(fp string) bool {
+	return strings.HasSuffix(fp, compressExt)
+}
+
+// removeBackup removes the backup,
+// and the compressed one if it's compressed after listing.
+func removeBackup(fp string) {
+	os.Remove(fp)
+	if !isCompressed(fp) {
+		os.Remove(fp + compressExt)
+	}
+}
+
 // parseTime extracts the formatted time from the filename by stripping off
-// the filename's prefix and extension.
+// the filename's prefix and extension (and compressExt if it's compressed).
 //
 // Return 0 if the file is illegal logro backup file.
 func parseTime(fp, prefix, ext string) int64 {
-	filename := filepath.Base(fp)
+	filename := strings.TrimSuffix(filepath.Base(fp), compressExt)
 	if !strings.HasPrefix(filename, prefix) {
 		return 0
 	}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"sync"
+)
+
+// OwnedBuffer is a buffer whose ownership could be passed to Rotation by WriteOwned.
+//
+// Rotation calls Free after the bytes are written to the log file,
+// the caller must not touch it after passing it to Rotation.
+// (zap's *buffer.Buffer satisfies OwnedBuffer)
+type OwnedBuffer interface {
+	Bytes() []byte
+	Free()
+}
+
+// Buffer is a pooled OwnedBuffer.
+// Get it by GetBuffer, fill it then pass it to Rotation.WriteOwned.
+type Buffer struct {
+	b []byte
+}
+
+// maxPooledBufferSize is the max capacity of Buffer could be put back to pool,
+// avoiding holding big buffers after writing a few big records.
+const maxPooledBufferSize = 64 * kb
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{b: make([]byte, 0, 1024)}
+	},
+}
+
+// GetBuffer gets an empty Buffer from pool.
+func GetBuffer() *Buffer {
+	b := bufferPool.Get().(*Buffer)
+	b.b = b.b[:0]
+	return b
+}
+
+// Write appends p to the Buffer, it never fails.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.b = append(b.b, p...)
+	return len(p), nil
+}
+
+// WriteString appends s to the Buffer, it never fails.
+func (b *Buffer) WriteString(s string) (int, error) {
+	b.b = append(b.b, s...)
+	return len(s), nil
+}
+
+// WriteByte appends c to the Buffer, it never fails.
+func (b *Buffer) WriteByte(c byte) error {
+	b.b = append(b.b, c)
+	return nil
+}
+
+// Bytes returns the contents of the Buffer.
+func (b *Buffer) Bytes() []byte {
+	return b.b
+}
+
+// Len returns the length of the Buffer.
+func (b *Buffer) Len() int {
+	return len(b.b)
+}
+
+// Reset resets the Buffer to be empty.
+func (b *Buffer) Reset() {
+	b.b = b.b[:0]
+}
+
+// Free puts the Buffer back to pool.
+func (b *Buffer) Free() {
+	if int64(cap(b.b)) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(b)
+}
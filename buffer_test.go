@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countBuffer struct {
+	p     []byte
+	freed *int64
+}
+
+func (b *countBuffer) Bytes() []byte { return b.p }
+
+func (b *countBuffer) Free() {
+	atomic.AddInt64(b.freed, 1)
+	b.p = nil
+}
+
+func TestRotation_WriteOwned(t *testing.T) {
+	fn := func(tr *testRotation) {
+		r := tr.r
+		freed := int64(0)
+		cnt := int(r.cfg.MaxSize / 2)
+		for i := 0; i < cnt; i++ {
+			n, err := r.WriteOwned(&countBuffer{p: []byte{'1'}, freed: &freed})
+			if err != nil {
+				tr.Fatal(err)
+			}
+			if n != 1 {
+				tr.Fatal("mismatch written")
+			}
+		}
+		r.Sync()
+		time.Sleep(10 * time.Millisecond)
+
+		if atomic.LoadInt64(&freed) != int64(cnt) {
+			tr.Fatal("buffers should be freed after writing", freed)
+		}
+		p, err := ioutil.ReadFile(r.cfg.OutputPath)
+		if err != nil {
+			tr.Fatal(err)
+		}
+		if !bytes.Equal(p, bytes.Repeat([]byte{'1'}, cnt)) {
+			tr.Fatal("mismatch content")
+		}
+
+		r.Close()
+		n, _ := r.WriteOwned(&countBuffer{p: []byte{'1'}, freed: &freed})
+		if n != 0 || atomic.LoadInt64(&freed) != int64(cnt)+1 {
+			tr.Fatal("should free buffer after closing")
+		}
+	}
+	runTest(t, fn)
+}
+
+func TestBuffer(t *testing.T) {
+	b := GetBuffer()
+	b.WriteString("abc")
+	b.WriteByte('d')
+	b.Write([]byte("ef"))
+	if string(b.Bytes()) != "abcdef" || b.Len() != 6 {
+		t.Fatal("mismatch buffer", string(b.Bytes()))
+	}
+	b.Free()
+
+	b = GetBuffer()
+	if b.Len() != 0 {
+		t.Fatal("buffer from pool should be empty")
+	}
+}
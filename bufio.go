@@ -12,6 +12,7 @@ package logro
 
 import (
 	"io"
+	"unsafe"
 )
 
 // bufIO implements buffering for an io.Writer object.
@@ -25,6 +26,13 @@ type bufIO struct {
 	buf []byte
 	n   int
 	w   io.Writer
+
+	// Direct I/O mode (created by newDirectBufIO).
+	wa io.WriterAt
+	// off is the file offset of buf[0], it's aligned to directAlign.
+	off int64
+	// persisted is the number of bytes in buf[0:n] which have been written to file.
+	persisted int
 }
 
 func newBufIO(w io.Writer, size int) *bufIO {
@@ -35,9 +43,42 @@ func newBufIO(w io.Writer, size int) *bufIO {
 	}
 }
 
-// reset resets io.Writer in bufIO, remains all buffered bytes.
-func (b *bufIO) reset(w io.Writer) {
-	b.w = w
+// directAlign is the alignment of address, offset & length of direct I/O,
+// it's enough for all logical block sizes in practice.
+const directAlign = 1 << 12
+
+// newDirectBufIO creates a bufIO for a file opened with O_DIRECT.
+//
+// The buffer is aligned to directAlign, and it's flushed by writing aligned blocks at offsets,
+// the last partial block is padded with zeros, it'll be rewritten in the next flush.
+// size is the logical length of the file, tail is the data of the last partial block
+// (size - size&^(directAlign-1) bytes).
+func newDirectBufIO(w io.WriterAt, bufSize int, size int64, tail []byte) *bufIO {
+
+	bufSize = (bufSize + directAlign - 1) &^ (directAlign - 1)
+	b := &bufIO{
+		buf: alignedBlock(bufSize),
+		wa:  w,
+		off: size &^ (directAlign - 1),
+	}
+	b.n = copy(b.buf, tail)
+	b.persisted = b.n
+	return b
+}
+
+// alignedBlock returns a buffer whose address is aligned to directAlign.
+func alignedBlock(size int) []byte {
+	b := make([]byte, size+directAlign)
+	off := int(uintptr(unsafe.Pointer(&b[0])) & (directAlign - 1))
+	if off != 0 {
+		off = directAlign - off
+	}
+	return b[off : off+size]
+}
+
+// size returns the logical length of the file in direct I/O mode.
+func (b *bufIO) size() int64 {
+	return b.off + int64(b.persisted)
 }
 
 // write writes the contents of p into the buffer.
@@ -46,6 +87,10 @@ func (b *bufIO) reset(w io.Writer) {
 // why the write is short (caused by underlying io.Writer).
 func (b *bufIO) write(p []byte) (nn int, fw int, err error) {
 
+	if b.wa != nil {
+		return b.writeDirect(p)
+	}
+
 	for len(p) > b.avail() && b.err == nil {
 		var n int
 		if b.buffered() == 0 {
@@ -72,12 +117,33 @@ func (b *bufIO) write(p []byte) (nn int, fw int, err error) {
 	return nn, fw, nil
 }
 
+// writeDirect is write in direct I/O mode, p is always copied to the aligned buffer.
+func (b *bufIO) writeDirect(p []byte) (nn int, fw int, err error) {
+
+	for len(p) > 0 && b.err == nil {
+		if b.avail() == 0 {
+			var fn int
+			fn, b.err = b.flush()
+			fw += fn
+			continue
+		}
+		n := copy(b.buf[b.n:], p)
+		b.n += n
+		nn += n
+		p = p[n:]
+	}
+	return nn, fw, b.err
+}
+
 // flush writes any buffered data to the underlying io.Writer.
 // Returns flushed and any error.
 func (b *bufIO) flush() (flushed int, err error) {
 	if b.err != nil {
 		return 0, b.err
 	}
+	if b.wa != nil {
+		return b.flushDirect()
+	}
 	if b.n == 0 {
 		return 0, nil
 	}
@@ -97,6 +163,32 @@ func (b *bufIO) flush() (flushed int, err error) {
 	return n, nil
 }
 
+// flushDirect is flush in direct I/O mode.
+// It returns the number of bytes newly written to the file (without padding & rewritten bytes).
+func (b *bufIO) flushDirect() (flushed int, err error) {
+
+	if b.n == b.persisted {
+		return 0, nil
+	}
+	m := (b.n + directAlign - 1) &^ (directAlign - 1)
+	for i := b.n; i < m; i++ {
+		b.buf[i] = 0
+	}
+	_, err = b.wa.WriteAt(b.buf[:m], b.off)
+	if err != nil {
+		b.err = err
+		return 0, err
+	}
+	flushed = b.n - b.persisted
+
+	// Keep the last partial block, it'll be rewritten with the following data.
+	full := b.n &^ (directAlign - 1)
+	b.n = copy(b.buf, b.buf[full:b.n])
+	b.off += int64(full)
+	b.persisted = b.n
+	return flushed, nil
+}
+
 // avail returns how many bytes are unused in the buffer.
 func (b *bufIO) avail() int { return len(b.buf) - b.n }
 
@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"io"
 	"testing"
+	"unsafe"
 )
 
 const minReadBufferSize = 16
@@ -128,3 +129,71 @@ func TestBufIOFlush(t *testing.T) {
 		}
 	}
 }
+
+// blockFile is an in-memory io.WriterAt checking the alignment of direct I/O.
+type blockFile struct {
+	t    *testing.T
+	data []byte
+}
+
+func (f *blockFile) WriteAt(p []byte, off int64) (int, error) {
+	if off%directAlign != 0 || len(p)%directAlign != 0 ||
+		uintptr(unsafe.Pointer(&p[0]))%directAlign != 0 {
+		f.t.Fatalf("unaligned write: off: %d, len: %d", off, len(p))
+	}
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	copy(f.data[off:], p)
+	return len(p), nil
+}
+
+func TestBufIODirect(t *testing.T) {
+	var data [20000]byte
+	for i := 0; i < len(data); i++ {
+		data[i] = byte(' ' + i%('~'-' '))
+	}
+
+	for _, tailSize := range []int{0, 1, 100, directAlign - 1} {
+		for _, nwrite := range []int{1, 23, 4095, 4096, 4097, 8192, 12345} {
+			f := &blockFile{t: t, data: append([]byte{}, data[:tailSize]...)}
+			buf := newDirectBufIO(f, 5000, int64(tailSize), data[:tailSize])
+			if len(buf.buf) != 2*directAlign {
+				t.Fatal("buffer size should be aligned")
+			}
+
+			fw := 0
+			for off := tailSize; off < tailSize+nwrite; off += 1000 { // Flushing partial blocks.
+				end := off + 1000
+				if end > tailSize+nwrite {
+					end = tailSize + nwrite
+				}
+				_, n, err := buf.write(data[off:end])
+				if err != nil {
+					t.Fatal(err)
+				}
+				fw += n
+				n, err = buf.flush()
+				if err != nil {
+					t.Fatal(err)
+				}
+				fw += n
+			}
+			if fw != nwrite {
+				t.Fatalf("tail=%d nwrite=%d: flushed mismatch: %d", tailSize, nwrite, fw)
+			}
+			size := buf.size()
+			if size != int64(tailSize+nwrite) {
+				t.Fatalf("tail=%d nwrite=%d: size mismatch: %d", tailSize, nwrite, size)
+			}
+			if !bytes.Equal(f.data[:size], data[:size]) {
+				t.Fatalf("tail=%d nwrite=%d: data mismatch", tailSize, nwrite)
+			}
+			for _, c := range f.data[size:] {
+				if c != 0 {
+					t.Fatal("padding should be zeros")
+				}
+			}
+		}
+	}
+}
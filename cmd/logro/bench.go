@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/templexxx/logro"
+)
+
+// benchCase is a combination of bench parameters.
+type benchCase struct {
+	writers      int
+	recordSize   int
+	bufItem      int
+	shards       int
+	perWriteSize int64 // KB.
+	perSyncSize  int64 // MB.
+}
+
+type benchResult struct {
+	benchCase
+	writes   int64
+	written  int64
+	dropped  int64
+	duration time.Duration
+	lats     []time.Duration // Sampled Write latency.
+	syncs    int64
+	syncTime time.Duration
+}
+
+// latencySampleRate is the rate of sampling Write latency,
+// measuring every Write makes writers slower.
+const latencySampleRate = 16
+
+func runBench(ctx context.Context, args []string, w io.Writer) error {
+
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: logro bench [flags]")
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", os.TempDir(), "directory for bench log files (on the disk to be measured)")
+	writers := fs.String("writers", "1,4,16", "list of writer goroutines")
+	sizes := fs.String("record-size", "256", "list of record sizes (bytes)")
+	bufItems := fs.String("buf-item", "2048", "list of Config.BufItem")
+	shards := fs.String("shards", "1", "list of Config.Shards")
+	perWrites := fs.String("per-write-size", "64", "list of Config.PerWriteSize (KB)")
+	perSyncs := fs.String("per-sync-size", "16", "list of Config.PerSyncSize (MB)")
+	rate := fs.Int("rate", 0, "records per second per writer (0: unlimited)")
+	duration := fs.Duration("duration", 5*time.Second, "duration of each case")
+	maxSize := fs.Int64("max-size", 128, "Config.MaxSize (MB)")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	cases, err := makeBenchCases(*writers, *sizes, *bufItems, *shards, *perWrites, *perSyncs)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "writers\trecord\tbuf_item\tshards\tper_write\tper_sync\twrites/s\tMB/s\tdropped\t"+
+		"p50\tp99\tp999\tmax\tsyncs\tavg_sync\t")
+	for _, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := benchOne(ctx, *dir, c, *rate, *duration, *maxSize)
+		if err != nil {
+			return err
+		}
+		writeBenchResult(tw, res)
+	}
+	return tw.Flush()
+}
+
+func makeBenchCases(writers, sizes, bufItems, shards, perWrites, perSyncs string) (cases []benchCase, err error) {
+
+	var ws, ss, bs, shs, pws, pss []int64
+	for _, l := range []struct {
+		s string
+		v *[]int64
+	}{{writers, &ws}, {sizes, &ss}, {bufItems, &bs}, {shards, &shs}, {perWrites, &pws}, {perSyncs, &pss}} {
+		*l.v, err = parseIntList(l.s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, w := range ws {
+		for _, s := range ss {
+			for _, b := range bs {
+				for _, sh := range shs {
+					for _, pw := range pws {
+						for _, ps := range pss {
+							cases = append(cases, benchCase{int(w), int(s), int(b), int(sh), pw, ps})
+						}
+					}
+				}
+			}
+		}
+	}
+	return cases, nil
+}
+
+func parseIntList(s string) ([]int64, error) {
+	var vs []int64
+	for _, f := range strings.Split(s, ",") {
+		v, err := strconv.ParseInt(strings.TrimSpace(f), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("illegal list %q: %s", s, err.Error())
+		}
+		if v <= 0 {
+			return nil, fmt.Errorf("illegal list %q: must be positive", s)
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+func benchOne(ctx context.Context, dir string, c benchCase, rate int, duration time.Duration,
+	maxSize int64) (res benchResult, err error) {
+
+	dir, err = ioutil.TempDir(dir, "logro-bench")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := logro.New(&logro.Config{
+		OutputPath:   filepath.Join(dir, "bench.log"),
+		MaxSize:      maxSize,
+		MaxBackups:   2,
+		BufItem:      c.bufItem,
+		Shards:       c.shards,
+		PerWriteSize: c.perWriteSize,
+		PerSyncSize:  c.perSyncSize,
+	})
+	if err != nil {
+		return
+	}
+
+	p := make([]byte, c.recordSize)
+	rand.Read(p)
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	results := make([]benchResult, c.writers)
+	start := time.Now()
+	for i := 0; i < c.writers; i++ {
+		wg.Add(1)
+		go func(res *benchResult) {
+			defer wg.Done()
+			benchWriter(ctx, r, p, rate, res)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	res.benchCase = c
+	res.duration = time.Since(start)
+	for _, wr := range results {
+		res.writes += wr.writes
+		res.lats = append(res.lats, wr.lats...)
+	}
+
+	// Waiting for writing all buffered records.
+	r.Sync()
+	for last := int64(-1); ; {
+		time.Sleep(10 * time.Millisecond)
+		st := r.Stats()
+		if st.Written == last {
+			break
+		}
+		last = st.Written
+	}
+	st := r.Stats()
+	res.written, res.dropped = st.Written, st.Dropped
+	res.syncs, res.syncTime = st.Syncs, st.SyncTime
+	return res, r.Close()
+}
+
+func benchWriter(ctx context.Context, r *logro.Rotation, p []byte, rate int, res *benchResult) {
+
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Second / time.Duration(rate)
+	}
+	next := time.Now()
+	for i := 0; ; i++ {
+		if i%64 == 0 && ctx.Err() != nil {
+			return
+		}
+		if interval > 0 {
+			if d := time.Until(next); d > 0 {
+				time.Sleep(d)
+			}
+			next = next.Add(interval)
+		}
+		if i%latencySampleRate == 0 {
+			start := time.Now()
+			r.Write(p)
+			res.lats = append(res.lats, time.Since(start))
+		} else {
+			r.Write(p)
+		}
+		res.writes++
+	}
+}
+
+func writeBenchResult(w io.Writer, res benchResult) {
+
+	sort.Slice(res.lats, func(i, j int) bool {
+		return res.lats[i] < res.lats[j]
+	})
+	pct := func(p float64) time.Duration {
+		if len(res.lats) == 0 {
+			return 0
+		}
+		return res.lats[int(float64(len(res.lats)-1)*p)]
+	}
+	avgSync := time.Duration(0)
+	if res.syncs > 0 {
+		avgSync = res.syncTime / time.Duration(res.syncs)
+	}
+	secs := res.duration.Seconds()
+
+	fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%dKB\t%dMB\t%.0f\t%.1f\t%d\t%s\t%s\t%s\t%s\t%d\t%s\t\n",
+		res.writers, res.recordSize, res.bufItem, res.shards, res.perWriteSize, res.perSyncSize,
+		float64(res.writes)/secs, float64(res.written)/secs/(1<<20), res.dropped,
+		pct(0.5), pct(0.99), pct(0.999), pct(1), res.syncs, avgSync)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+// Command logro inspects and maintains logro-managed log files.
+//
+// Usage:
+//
+//	logro <command> [flags] <output_path>
+//
+// output_path is the log file path in logro's Config (e.g. /var/log/app/a.log),
+// backups are found by it.
+//
+// logro bench [flags] measures Rotation on the disk of -dir,
+// helping to choose BufItem, PerWriteSize & PerSyncSize.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string, w io.Writer) error
+}
+
+var commands = map[string]command{
+	"ls":       {"list the active log file & backups", runLs},
+	"cat":      {"print records across backups & the active log file", runCat},
+	"tail":     {"print the last records, follow new records across rotations with -f", runTail},
+	"prune":    {"remove backups by retention policy", runPrune},
+	"compress": {"compress backups with gzip", runCompress},
+	"verify":   {"verify checksums of framed log files", runVerify},
+	"bench":    {"measure throughput & latency of Rotation with different configs", runBench},
+}
+
+func main() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	err := run(ctx, os.Args[1:], os.Stdout)
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "logro:", err)
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("usage: logro <command> [flags] <output_path>")
+
+func run(ctx context.Context, args []string, w io.Writer) error {
+
+	if len(args) == 0 {
+		printUsage()
+		return errUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return cmd.run(ctx, args[1:], w)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, errUsage.Error())
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s%s\n", name, commands[name].usage)
+	}
+}
+
+// parseFlags parses flags of command, returns output_path.
+func parseFlags(fs *flag.FlagSet, args []string) (string, error) {
+	err := fs.Parse(args)
+	if err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", fmt.Errorf("%s: need one output_path", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: logro %s [flags] <output_path>\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/templexxx/logro"
+)
+
+// makeTestDir writes n records through logro with rotations.
+func makeTestDir(t *testing.T, n int) (dir, output string) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output = filepath.Join(dir, "logro-test.log")
+	r, err := logro.New(&logro.Config{OutputPath: output, MaxSize: 64, MaxBackups: 64,
+		PerWriteSize: 16, PerSyncSize: 32, Developed: true, Framed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		r.Write([]byte(fmt.Sprintf("record-%02d", i)))
+		time.Sleep(2 * time.Millisecond)
+	}
+	r.Sync()
+	time.Sleep(10 * time.Millisecond)
+	r.Close()
+	return
+}
+
+func runCmd(t *testing.T, args ...string) string {
+	buf := new(bytes.Buffer)
+	err := run(context.Background(), args, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCommands(t *testing.T) {
+	dir, output := makeTestDir(t, 32)
+	defer os.RemoveAll(dir)
+
+	out := runCmd(t, "ls", output)
+	if !strings.Contains(out, "active") || !strings.Contains(out, "backup") {
+		t.Fatal("ls should list the active log file & backups", out)
+	}
+
+	out = runCmd(t, "cat", "-framed", output)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 32 || lines[0] != "record-00" || lines[31] != "record-31" {
+		t.Fatal("cat mismatch", out)
+	}
+
+	out = runCmd(t, "tail", "-framed", "-n", "2", output)
+	if out != "record-30\nrecord-31\n" {
+		t.Fatal("tail mismatch", out)
+	}
+
+	runCmd(t, "compress", "-keep", "1", output)
+	out = runCmd(t, "verify", output)
+	if !strings.Contains(out, ".gz") {
+		t.Fatal("verify should read compressed backups", out)
+	}
+	out = runCmd(t, "cat", "-framed", output)
+	if strings.Count(out, "\n") != 32 {
+		t.Fatal("cat mismatch after compressing", out)
+	}
+	out = runCmd(t, "tail", "-framed", "-n", "40", output)
+	if out != runCmd(t, "cat", "-framed", output) {
+		t.Fatal("tail mismatch after compressing", out)
+	}
+
+	runCmd(t, "prune", "-max-backups", "1", output)
+	files, err := logro.ListFiles(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Compressed {
+		t.Fatal("should only keep the newest backup", files)
+	}
+}
+
+func TestVerifyCorrupt(t *testing.T) {
+	dir, output := makeTestDir(t, 4)
+	defer os.RemoveAll(dir)
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("garbage"))
+	f.Close()
+
+	err = run(context.Background(), []string{"verify", output}, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), output) {
+		t.Fatal("should find corrupt file", err)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	if run(context.Background(), []string{"x"}, ioutil.Discard) == nil {
+		t.Fatal("should fail")
+	}
+	if run(context.Background(), []string{"ls"}, ioutil.Discard) == nil {
+		t.Fatal("should fail without output_path")
+	}
+}
+
+func TestBench(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "logro-bench-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	err = run(context.Background(), []string{"bench", "-dir", dir, "-writers", "1,2", "-record-size", "64",
+		"-duration", "50ms", "-max-size", "1"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 3 {
+		t.Fatal("should have header & two cases", out.String())
+	}
+
+	if run(context.Background(), []string{"bench", "-writers", "0"}, ioutil.Discard) == nil {
+		t.Fatal("should fail with illegal list")
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/templexxx/logro"
+)
+
+func runLs(ctx context.Context, args []string, w io.Writer) error {
+
+	fs := newFlagSet("ls")
+	outputPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	files, err := logro.ListFiles(outputPath)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tSIZE\tTIME\tSTATE")
+	total := int64(0)
+	for _, f := range files {
+		ts, state := "-", "active"
+		if !f.Active {
+			ts, state = f.Time.UTC().Format(time.RFC3339), "backup"
+			if f.Compressed {
+				state += ",compressed"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", f.Path, f.Size, ts, state)
+		total += f.Size
+	}
+	fmt.Fprintf(tw, "total: %d files\t%d\t\t\n", len(files), total)
+	return tw.Flush()
+}
+
+func runPrune(ctx context.Context, args []string, w io.Writer) error {
+
+	fs := newFlagSet("prune")
+	var opts logro.PruneOptions
+	fs.IntVar(&opts.MaxBackups, "max-backups", 0, "maximum number of backups to retain (0: no limit)")
+	fs.DurationVar(&opts.MaxAge, "max-age", 0, "maximum age of backups to retain (0: no limit)")
+	fs.Int64Var(&opts.MaxTotalSize, "max-total-size", 0,
+		"maximum total size (bytes) of backups & the active log file (0: no limit)")
+	outputPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if opts == (logro.PruneOptions{}) {
+		return fmt.Errorf("prune: need at least one of -max-backups, -max-age, -max-total-size")
+	}
+
+	removed, err := logro.Prune(outputPath, opts)
+	for _, fp := range removed {
+		fmt.Fprintln(w, "removed", fp)
+	}
+	return err
+}
+
+func runCompress(ctx context.Context, args []string, w io.Writer) error {
+
+	fs := newFlagSet("compress")
+	keep := fs.Int("keep", 0, "keep the newest n backups uncompressed (log shippers may be reading them)")
+	outputPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	files, err := logro.ListFiles(outputPath)
+	if err != nil {
+		return err
+	}
+	var backups []logro.LogFile
+	for _, f := range files {
+		if !f.Active && !f.Compressed {
+			backups = append(backups, f)
+		}
+	}
+	if *keep > 0 {
+		if *keep >= len(backups) {
+			return nil
+		}
+		backups = backups[:len(backups)-*keep]
+	}
+
+	for _, f := range backups {
+		if ctx.Err() != nil {
+			return nil
+		}
+		dst, err := logro.Compress(f.Path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "compressed", dst)
+	}
+	return nil
+}
+
+func runVerify(ctx context.Context, args []string, w io.Writer) error {
+
+	fs := newFlagSet("verify")
+	outputPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	files, err := logro.ListFiles(outputPath)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tRECORDS\tCORRUPT_BYTES")
+	var corrupt []string
+	for _, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		records, skipped, err := verifyFile(f.Path)
+		if err != nil {
+			tw.Flush()
+			return err
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", f.Path, records, skipped)
+		if skipped > 0 {
+			corrupt = append(corrupt, f.Path)
+		}
+	}
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+	if len(corrupt) > 0 {
+		return fmt.Errorf("verify: corrupt files: %s", strings.Join(corrupt, ", "))
+	}
+	return nil
+}
+
+// verifyFile reads all frames in fp,
+// returns the number of valid records and corrupt bytes.
+func verifyFile(fp string) (records, skipped int64, err error) {
+
+	f, err := os.Open(fp)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(fp, ".gz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to decompress %s: %s", fp, err.Error())
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	fr := logro.NewFrameReader(r)
+	for {
+		_, err = fr.Next()
+		if err == io.EOF {
+			return records, fr.Skipped(), nil
+		}
+		if err != nil {
+			return records, fr.Skipped(), fmt.Errorf("failed to read %s: %s", fp, err.Error())
+		}
+		records++
+	}
+}
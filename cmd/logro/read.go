@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/templexxx/logro"
+)
+
+func runCat(ctx context.Context, args []string, w io.Writer) error {
+
+	fs := newFlagSet("cat")
+	framed := fs.Bool("framed", false, "log files are written in framed mode")
+	direct := fs.Bool("direct-io", false, "log files are written in direct I/O mode")
+	from := fs.String("from", "", "only print records since this time (RFC3339)")
+	to := fs.String("to", "", "only print records until this time (RFC3339)")
+	meta := fs.Bool("meta", false, "print file & offset (& sequence number) before each record")
+	outputPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	it, err := logro.Open(outputPath, &logro.ReadOptions{Framed: *framed, DirectIO: *direct})
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	if *from != "" || *to != "" {
+		start, end, err := parseRange(*from, *to)
+		if err != nil {
+			return err
+		}
+		it.Seek(start, end)
+	}
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	for it.Next() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		writeRecord(bw, it.Record(), *meta)
+	}
+	return it.Err()
+}
+
+func parseRange(from, to string) (start, end time.Time, err error) {
+	end = time.Unix(1<<62, 0)
+	if from != "" {
+		start, err = time.Parse(time.RFC3339Nano, from)
+		if err != nil {
+			return
+		}
+	}
+	if to != "" {
+		end, err = time.Parse(time.RFC3339Nano, to)
+	}
+	return
+}
+
+func writeRecord(w io.Writer, rec logro.Record, meta bool) {
+	if meta {
+		fmt.Fprintf(w, "%s:%d: ", rec.File, rec.Offset)
+		if rec.Seq != 0 {
+			fmt.Fprintf(w, "seq=%d ", rec.Seq)
+		}
+	}
+	w.Write(rec.Data)
+	if len(rec.Data) == 0 || rec.Data[len(rec.Data)-1] != '\n' {
+		w.Write([]byte{'\n'})
+	}
+}
+
+func runTail(ctx context.Context, args []string, w io.Writer) error {
+
+	fs := newFlagSet("tail")
+	framed := fs.Bool("framed", false, "log files are written in framed mode")
+	direct := fs.Bool("direct-io", false, "log files are written in direct I/O mode")
+	n := fs.Int("n", 10, "print the last n records")
+	follow := fs.Bool("f", false, "follow new records across rotations")
+	meta := fs.Bool("meta", false, "print file & offset (& sequence number) before each record")
+	outputPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// Following from where the last records end, not missing records written after reading them.
+	opts := logro.ReadOptions{Framed: *framed, DirectIO: *direct}
+	last, cp, err := logro.Tail(outputPath, *n, &opts)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, rec := range last {
+		writeRecord(bw, rec, *meta)
+	}
+	err = bw.Flush()
+	if err != nil || !*follow {
+		return err
+	}
+
+	fl, err := logro.Follow(ctx, outputPath, &logro.FollowOptions{
+		ReadOptions: opts,
+		From:        &cp,
+	})
+	if err != nil {
+		return err
+	}
+	defer fl.Close()
+
+	for fl.Next() {
+		writeRecord(w, fl.Record(), *meta)
+	}
+	if fl.Err() == context.Canceled {
+		return nil
+	}
+	return fl.Err()
+}
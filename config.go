@@ -8,7 +8,12 @@
 
 package logro
 
+import "time"
+
 // Config of logro.
+//
+// In JSON/TOML config files, sizes could be human-readable strings (e.g. "128MiB", "64KB"),
+// and LockWait could be a duration string (e.g. "1m"), see Config.UnmarshalJSON.
 type Config struct {
 	// OutputPath is the log file path.
 	OutputPath string `json:"output_path" toml:"output_path"`
@@ -16,11 +21,30 @@ type Config struct {
 	// Unit: MB.
 	// Default: 128 (128MB).
 	MaxSize int64 `json:"max_size_mb" toml:"max_size_mb"`
+	// MaxSizeBytes is MaxSize in bytes, it's used instead of MaxSize if it's > 0.
+	MaxSizeBytes int64 `json:"max_size_bytes" toml:"max_size_bytes"`
 	// MaxBackups is the maximum number of backup log files to retain.
 	MaxBackups int `json:"max_backups" toml:"max_backups"`
 	// LocalTime is the timestamp in backup log file. Default is to use UTC time.
 	// If true, use local time.
 	LocalTime bool `json:"local_time" toml:"local_time"`
+	// LockWait is the max duration of waiting for the lock of OutputPath
+	// when it's held by another process.
+	// Default: 0 (return ErrLocked immediately).
+	// If < 0, wait until getting the lock.
+	//
+	// logro holds an advisory lock on OutputPath.lock while running,
+	// avoiding two processes writing & rotating the same log file.
+	LockWait time.Duration `json:"lock_wait" toml:"lock_wait"`
+	// StartupMode is the way of dealing with the existed log file when logro starts.
+	// Default: StartupTruncate.
+	StartupMode StartupMode `json:"startup_mode" toml:"startup_mode"`
+	// Framed makes logro write each record with a length-prefixed,
+	// CRC32C-checked frame, which could be read by FrameReader.
+	// Default is false (write raw bytes).
+	//
+	// It's useful for binary payloads and multi-line records.
+	Framed bool `json:"framed" toml:"framed"`
 
 	// BufItem is the number of logro's write buffer items,
 	// logro buffers can hold write input up to BufItem.
@@ -29,6 +53,28 @@ type Config struct {
 	// Buffer will overwrite data on writes in lieu of blocking.
 	// Losing data will be up to BufItem.
 	BufItem int `json:"buf_item" toml:"buf_item"`
+	// Shards is the number of ring buffers, each of them has BufItem items.
+	// Default: 1.
+	//
+	// All writers contend on one ring buffer, with Shards > 1,
+	// writers are spread across shards by goroutine (a hint from stack address),
+	// and writeLoop drains them round-robin.
+	// Records in one shard keep Write order, but there is no order across shards,
+	// even records written by one goroutine may be reordered.
+	Shards int `json:"shards" toml:"shards"`
+	// Sequenced makes Write assign a global sequence number (from 1) to each record,
+	// and writeLoop writes records in sequence order within a reorder window.
+	// In framed mode, the sequence number is written in the frame (Record.Seq),
+	// it could be used for dedup in downstream.
+	// Default is false.
+	//
+	// It's useful with Shards > 1. Records overwritten in buffer leave gaps in sequence.
+	Sequenced bool `json:"sequenced" toml:"sequenced"`
+	// ReorderWindow is the max number of records held for reordering in sequenced mode,
+	// a record is written when its predecessors are written,
+	// or the window is full, or there is no more record in buffer.
+	// Default: 1024.
+	ReorderWindow int `json:"reorder_window" toml:"reorder_window"`
 	// PerWriteSize is logro's write size,
 	// logro writes data to page cache every PerWriteSize.
 	// Unit: KB.
@@ -38,6 +84,8 @@ type Config struct {
 	// The size of it should be aligned to page size,
 	// and it shouldn't be too large, because that may block logro write.
 	PerWriteSize int64 `json:"per_write_size" toml:"per_write_size"`
+	// PerWriteSizeBytes is PerWriteSize in bytes, it's used instead of PerWriteSize if it's > 0.
+	PerWriteSizeBytes int64 `json:"per_write_size_bytes" toml:"per_write_size_bytes"`
 	// PerSyncSize is logro's sync size,
 	// logro flushes data to storage media(hint) every PerSyncSize.
 	// Unit: MB.
@@ -47,12 +95,103 @@ type Config struct {
 	// The size of it should be aligned to page size,
 	// and it shouldn't be too large, avoiding burst I/O.
 	PerSyncSize int64 `json:"per_sync_size" toml:"per_sync_size"`
+	// PerSyncSizeBytes is PerSyncSize in bytes, it's used instead of PerSyncSize if it's > 0.
+	PerSyncSizeBytes int64 `json:"per_sync_size_bytes" toml:"per_sync_size_bytes"`
+	// DisableAlignment disables aligning MaxSize, PerWriteSize & PerSyncSize to page size.
+	// Default is false.
+	//
+	// Unaligned sizes make page cache control less effective (partial pages are written twice),
+	// but it's useful for small log files or fitting sizes of other systems.
+	DisableAlignment bool `json:"disable_alignment" toml:"disable_alignment"`
+	// Preallocate makes logro reserve MaxSize for each log file when it's opened
+	// (by fallocate(2) with FALLOC_FL_KEEP_SIZE, the file size is unchanged),
+	// reducing fragmentation and metadata updates in appending & syncing.
+	// The unused space is released when the log file is closed (e.g. rotated).
+	// Default is false.
+	//
+	// It only works on Linux (and file systems supporting fallocate), it's ignored on others.
+	Preallocate bool `json:"preallocate" toml:"preallocate"`
+	// CachePolicy is the way of controlling page cache of log files.
+	// Default: CacheDropRotated.
+	CachePolicy CachePolicy `json:"cache_policy" toml:"cache_policy"`
+	// DirectIO makes logro write log files with O_DIRECT, bypassing page cache entirely.
+	// Default is false.
+	//
+	// Data is written in aligned blocks (4KB) from the write buffer (PerWriteSize, aligned to 4KB),
+	// the last partial block is padded with zeros and rewritten by the next write,
+	// so the active log file may end with zeros (less than a block) until it's rotated or closed,
+	// then it's truncated to the length of data.
+	// Open, Follow & `logro tail` treat the padding as not written yet with ReadOptions.DirectIO
+	// (`-direct-io`), other readers (e.g. log shippers watching file size) may see it.
+	//
+	// It only works on Linux (and file systems supporting O_DIRECT),
+	// and it isn't supported by Manager.
+	DirectIO bool `json:"direct_io" toml:"direct_io"`
+
+	// Lenient makes New accept invalid values by replacing them with defaults
+	// (or adjusting them), instead of returning the error of Validate.
+	// Default is false.
+	Lenient bool `json:"lenient" toml:"lenient"`
 
 	// Develop mode. Default is false.
-	// It' used for testing, if it's true, the page cache control unit could not be aligned to page cache size.
+	// It' used for testing, if it's true, the units of MaxSize, PerWriteSize & PerSyncSize are bytes,
+	// the page cache control unit could not be aligned to page cache size,
+	// and PerSyncSize could be < 2 * PerWriteSize.
+	//
+	// Deprecated: Use MaxSizeBytes, PerWriteSizeBytes, PerSyncSizeBytes & DisableAlignment.
 	Developed bool `json:"developed" toml:"developed"`
 }
 
+// StartupMode is the way of dealing with the existed log file when logro starts.
+type StartupMode string
+
+const (
+	// StartupTruncate truncates the existed log file.
+	StartupTruncate StartupMode = "truncate"
+	// StartupAppend continues writing the existed log file,
+	// the size of it will be counted for rotation.
+	StartupAppend StartupMode = "append"
+	// StartupRotateExisting moves the existed log file to backups,
+	// then creates a new one.
+	StartupRotateExisting StartupMode = "rotate"
+)
+
+func (m StartupMode) isValid() bool {
+	switch m {
+	case "", StartupTruncate, StartupAppend, StartupRotateExisting:
+		return true
+	default:
+		return false
+	}
+}
+
+// CachePolicy is the way of controlling page cache of log files.
+type CachePolicy string
+
+const (
+	// CacheDropRotated flushes dirty pages of the active log file every PerSyncSize,
+	// and drops the page cache of the log file after rotating it.
+	CacheDropRotated CachePolicy = "drop-rotated"
+	// CacheDropBehind is CacheDropRotated, and drops the page cache of the active log file
+	// behind the write head (the ranges flushed in previous rounds), keeping little cache.
+	CacheDropBehind CachePolicy = "drop-behind"
+	// CacheKeepRotated flushes dirty pages like CacheDropRotated, but keeps the page cache
+	// of rotated log files, which may be read by log shippers soon.
+	CacheKeepRotated CachePolicy = "keep-rotated"
+	// CacheKernel leaves flushing & dropping to the kernel,
+	// rotated log files are still synced before closing.
+	CacheKernel CachePolicy = "kernel"
+)
+
+func (p CachePolicy) isValid() bool {
+	switch p {
+	case "", CacheDropRotated, CacheDropBehind, CacheKeepRotated, CacheKernel:
+		return true
+	default:
+		return false
+	}
+}
+
 const (
 	kb int64 = 1024
 	mb       = 1024 * kb
@@ -62,6 +201,8 @@ const (
 var (
 	defaultBufItem = 2048
 
+	defaultReorderWindow = 1024
+
 	defaultPerWriteSize = 64 * kb
 	defaultPerSyncSize  = 16 * mb
 
@@ -73,45 +214,56 @@ var (
 
 func (c *Config) adjust() {
 
-	k, m := kb, mb
-	if c.Developed {
-		k, m = 1, 1
+	c.MaxSize = c.byteSize(c.MaxSizeBytes, c.MaxSize, mb, defaultMaxSize)
+	if c.MaxBackups <= 0 {
+		c.MaxBackups = defaultMaxBackups
 	}
 
-	if c.MaxSize <= 0 {
-		c.MaxSize = defaultMaxSize
-	} else {
-		c.MaxSize = c.MaxSize * m
+	if c.StartupMode == "" {
+		c.StartupMode = StartupTruncate
 	}
-	if c.MaxBackups <= 0 {
-		c.MaxBackups = defaultMaxBackups
+	if c.CachePolicy == "" {
+		c.CachePolicy = CacheDropRotated
 	}
 
 	if c.BufItem <= 0 {
 		c.BufItem = defaultBufItem
 	}
-
-	if c.PerWriteSize <= 0 {
-		c.PerWriteSize = defaultPerWriteSize
-	} else {
-		c.PerWriteSize = c.PerWriteSize * k
+	if c.Shards <= 0 {
+		c.Shards = 1
 	}
-	if c.PerSyncSize <= 0 {
-		c.PerSyncSize = defaultPerSyncSize
-	} else {
-		c.PerSyncSize = c.PerSyncSize * m
+	if c.ReorderWindow <= 0 {
+		c.ReorderWindow = defaultReorderWindow
 	}
 
-	if !c.Developed {
-		if c.PerSyncSize < 2*c.PerWriteSize {
-			c.PerSyncSize = 2 * c.PerWriteSize
-		}
+	c.PerWriteSize = c.byteSize(c.PerWriteSizeBytes, c.PerWriteSize, kb, defaultPerWriteSize)
+	c.PerSyncSize = c.byteSize(c.PerSyncSizeBytes, c.PerSyncSize, mb, defaultPerSyncSize)
+
+	if !c.Developed && c.PerSyncSize < 2*c.PerWriteSize {
+		c.PerSyncSize = 2 * c.PerWriteSize
+	}
+	if !c.Developed && !c.DisableAlignment {
 		c.MaxSize = alignToPage(c.MaxSize)
 		c.PerWriteSize = alignToPage(c.PerWriteSize)
 		c.PerSyncSize = alignToPage(c.PerSyncSize)
 	}
 }
 
+// byteSize returns the size in bytes by the byte-exact field b (if it's > 0),
+// or n in unit (bytes in Developed mode), or def if neither of them is set.
+func (c *Config) byteSize(b, n, unit, def int64) int64 {
+	switch {
+	case b > 0:
+		return b
+	case n > 0 && c.Developed:
+		return n
+	case n > 0:
+		return n * unit
+	default:
+		return def
+	}
+}
+
 const pageSize = 1 << 12 // 4KB.
 
 func alignToPage(n int64) int64 {
@@ -9,6 +9,11 @@
 package logro
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
 )
 
@@ -68,3 +73,196 @@ func TestAlignToPage(t *testing.T) {
 		}
 	}
 }
+
+func TestConfig_Validate(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "a", "b.log") // Directory will be created.
+
+	valid := []*Config{
+		{OutputPath: fp},
+		{OutputPath: fp, MaxSize: 2, PerWriteSize: 1024, PerSyncSize: 2},
+		{OutputPath: fp, MaxSize: 32, PerWriteSize: 4, PerSyncSize: 16, Developed: true},
+		{OutputPath: fp, LockWait: -1, StartupMode: StartupAppend},
+	}
+	for i, cfg := range valid {
+		err = cfg.Validate()
+		if err != nil {
+			t.Fatal("should be valid", i, err)
+		}
+	}
+
+	invalid := []struct {
+		cfg  *Config
+		errs int
+	}{
+		{&Config{}, 1},
+		{&Config{OutputPath: dir}, 1},
+		{&Config{OutputPath: fp, StartupMode: "x"}, 1},
+		{&Config{OutputPath: fp, MaxSize: -1, BufItem: -1, PerSyncSize: -1}, 3},
+		{&Config{OutputPath: fp, MaxBackups: maxMaxBackups + 1}, 1},
+		{&Config{OutputPath: fp, MaxSize: 1, PerWriteSize: 2048}, 1},
+		{&Config{OutputPath: fp, PerWriteSize: 1024, PerSyncSize: 1}, 1},
+		{&Config{OutputPath: fp, MaxSize: 8, PerWriteSize: 16, Developed: true}, 1},
+	}
+	for i, c := range invalid {
+		err = c.cfg.Validate()
+		ce, ok := err.(*ConfigError)
+		if !ok {
+			t.Fatal("should be invalid", i, err)
+		}
+		if len(ce.Errs) != c.errs {
+			t.Fatal("mismatch errors", i, err)
+		}
+	}
+}
+
+func TestConfig_ValidateUnwritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root could write any directory")
+	}
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Chmod(dir, 0555)
+	defer os.Chmod(dir, 0755)
+
+	err = (&Config{OutputPath: filepath.Join(dir, "a.log")}).Validate()
+	if err == nil {
+		t.Fatal("should be unwritable")
+	}
+}
+
+func TestNew_Validate(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "a.log")
+
+	_, err = New(&Config{OutputPath: fp, MaxSize: -1})
+	if _, ok := err.(*ConfigError); !ok {
+		t.Fatal("should fail fast", err)
+	}
+
+	cfg := &Config{OutputPath: fp, MaxSize: -1, Lenient: true}
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.cfg.MaxSize != defaultMaxSize {
+		t.Fatal("should be adjusted to default")
+	}
+	if cfg.MaxSize != -1 {
+		t.Fatal("should not modify Config")
+	}
+}
+
+func TestConfigBytes(t *testing.T) {
+	cfg := &Config{
+		MaxSize:           1,
+		MaxSizeBytes:      10000,
+		PerWriteSizeBytes: 1000,
+		PerSyncSize:       1,
+		DisableAlignment:  true,
+	}
+	cfg.adjust()
+	if cfg.MaxSize != 10000 {
+		t.Fatal("mismatch")
+	}
+	if cfg.PerWriteSize != 1000 {
+		t.Fatal("mismatch")
+	}
+	if cfg.PerSyncSize != mb {
+		t.Fatal("mismatch")
+	}
+
+	// Aligned.
+	cfg = &Config{MaxSizeBytes: 10000, PerWriteSizeBytes: 1000, PerSyncSizeBytes: 1000}
+	cfg.adjust()
+	if cfg.MaxSize != alignToPage(10000) {
+		t.Fatal("mismatch")
+	}
+	if cfg.PerWriteSize != pageSize {
+		t.Fatal("mismatch")
+	}
+	if cfg.PerSyncSize != pageSize { // 2 * PerWriteSizeBytes, then aligned.
+		t.Fatal("mismatch")
+	}
+}
+
+func TestConfig_ValidateBytes(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "a.log")
+	cases := []struct {
+		cfg  *Config
+		errs int
+	}{
+		{&Config{OutputPath: fp, MaxSizeBytes: 10000, PerWriteSizeBytes: 1000, PerSyncSizeBytes: 2000}, 0},
+		{&Config{OutputPath: fp, MaxSizeBytes: -1, PerWriteSizeBytes: -1, PerSyncSizeBytes: -1}, 3},
+		{&Config{OutputPath: fp, MaxSize: 1, MaxSizeBytes: mb}, 1},
+		{&Config{OutputPath: fp, MaxSizeBytes: 100, PerWriteSizeBytes: 1000}, 1},
+		{&Config{OutputPath: fp, PerWriteSizeBytes: 1000, PerSyncSizeBytes: 1000}, 1},
+		{&Config{OutputPath: fp, PerWriteSize: 1, PerSyncSizeBytes: 2048}, 0},
+	}
+	for i, c := range cases {
+		err := c.cfg.Validate()
+		n := 0
+		if err != nil {
+			n = len(err.(*ConfigError).Errs)
+		}
+		if n != c.errs {
+			t.Fatalf("case %d: errors mismatch, exp: %d, got: %v", i, c.errs, err)
+		}
+	}
+
+	r, err := New(&Config{OutputPath: fp, MaxSizeBytes: 10000, PerWriteSizeBytes: 1000,
+		PerSyncSizeBytes: 2000, DisableAlignment: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.cfg.MaxSize != 10000 || r.cfg.PerWriteSize != 1000 || r.cfg.PerSyncSize != 2000 {
+		t.Fatal("mismatch")
+	}
+}
+
+func TestConfigError_Is(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	err = ioutil.WriteFile(file, nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Parent of the log directory is a file.
+	err = (&Config{OutputPath: filepath.Join(file, "a", "a.log"), MaxSize: -1}).Validate()
+	var ce *ConfigError
+	if !errors.As(err, &ce) || len(ce.Errs) != 2 {
+		t.Fatal("should be ConfigError with 2 problems", err)
+	}
+	if !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatal("should match the cause of problem", err)
+	}
+	var pe *os.PathError
+	if !errors.As(err, &pe) || pe.Path != filepath.Join(file, "a") {
+		t.Fatal("should match the cause of problem", err)
+	}
+}
@@ -0,0 +1,15 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import "syscall"
+
+// oDirect is the flag of opening file for direct I/O.
+const oDirect = syscall.O_DIRECT
+
+const directIOSupported = true
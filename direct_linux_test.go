@@ -0,0 +1,283 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// skipIfNoDirectIO skips the test if the file system of dir doesn't support O_DIRECT.
+func skipIfNoDirectIO(t *testing.T, dir string) {
+	f, err := os.OpenFile(filepath.Join(dir, "probe"), os.O_WRONLY|os.O_CREATE|oDirect, 0644)
+	if err != nil {
+		t.Skip("O_DIRECT is not supported:", err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+func TestRotation_DirectIO(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	skipIfNoDirectIO(t, dir)
+
+	fp := filepath.Join(dir, "a.log")
+	r, err := New(&Config{
+		OutputPath:        fp,
+		MaxSizeBytes:      20000, // Not aligned.
+		MaxBackups:        100,
+		BufItem:           8192,
+		PerWriteSizeBytes: 4096,
+		PerSyncSizeBytes:  8192,
+		Framed:            true,
+		DirectIO:          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records [][]byte
+	var size int64
+	for i := 0; i < 2000; i++ {
+		p := []byte(fmt.Sprintf("record-%d", i))
+		records = append(records, p)
+		r.Write(p)
+		size += int64(frameHeaderSize + extSize(frameFlagTime) + len(p))
+		if i%100 == 0 {
+			time.Sleep(5 * time.Millisecond) // Written by writeLoop, rotating by size.
+		}
+		if i%500 == 0 { // Flushing partial blocks.
+			waitWritten(t, r, size)
+		}
+	}
+	waitWritten(t, r, size)
+	if r.Stats().Dropped != 0 {
+		t.Skip("records dropped, too slow")
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := listBackups(fp, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs.Len() == 0 {
+		t.Fatal("should have backups")
+	}
+	var total int64
+	for _, f := range append(bs.sorted(), Backup{fp: fp}) {
+		p, err := ioutil.ReadFile(f.fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p) > 0 && p[len(p)-1] == 0 {
+			t.Fatal("padding should be truncated", f.fp)
+		}
+		total += int64(len(p))
+	}
+	if total != size {
+		t.Fatalf("size mismatch, exp: %d, got: %d", size, total)
+	}
+
+	it, err := Open(fp, &ReadOptions{Framed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	i := 0
+	for it.Next() {
+		if !bytes.Equal(it.Record().Data, records[i]) {
+			t.Fatal("record mismatch", i)
+		}
+		i++
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if i != len(records) {
+		t.Fatal("records count mismatch", i)
+	}
+}
+
+func TestRotation_DirectIOAppend(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	skipIfNoDirectIO(t, dir)
+
+	fp := filepath.Join(dir, "a.log")
+	exp := bytes.Repeat([]byte("0123456789abcde\n"), 300) // Not aligned.
+	err = ioutil.WriteFile(fp, exp, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{OutputPath: fp, StartupMode: StartupAppend, DirectIO: true}
+	for i := 0; i < 3; i++ {
+		r, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := []byte(fmt.Sprintf("appended-%d\n", i))
+		exp = append(exp, p...)
+		r.Write(p)
+		waitWritten(t, r, int64(len(p)))
+		r.Close()
+
+		act, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(act, exp) {
+			t.Fatal("content mismatch", i)
+		}
+	}
+}
+
+// The padding of the active log file is not written data for readers,
+// it's overwritten by the following writes.
+func TestRotation_DirectIOReaders(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	skipIfNoDirectIO(t, dir)
+
+	fp := filepath.Join(dir, "a.log")
+	r, err := New(&Config{OutputPath: fp, DirectIO: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := ReadOptions{DirectIO: true}
+	fl, err := Follow(ctx, fp, &FollowOptions{ReadOptions: opts, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	var exp [][]byte
+	var size int64
+	for i := 0; i < 20; i++ {
+		p := []byte(fmt.Sprintf("line-%d\n", i))
+		exp = append(exp, p[:len(p)-1])
+		r.Write(p)
+		size += int64(len(p))
+		waitWritten(t, r, size) // Flushing the padded block.
+		if act := followN(t, fl, 1); !bytes.Equal(act[0], exp[i]) {
+			t.Fatalf("record mismatch, exp: %q, got: %q", exp[i], act[0])
+		}
+	}
+
+	it, err := Open(fp, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var act [][]byte
+	for it.Next() {
+		act = append(act, append([]byte(nil), it.Record().Data...))
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if !isMatchRecords(exp, act) {
+		t.Fatalf("records mismatch: %q", act)
+	}
+
+	tail, err := Follow(ctx, fp, &FollowOptions{ReadOptions: opts, FromEnd: true, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tail.Close()
+	r.Write([]byte("line-20\n"))
+	waitWritten(t, r, size+8)
+	if act := followN(t, tail, 1); string(act[0]) != "line-20" {
+		t.Fatalf("record mismatch: %q", act[0])
+	}
+}
+
+// The old log file is kept writing from where it ends if rotation fails.
+func TestRotation_DirectIORotateFailed(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	skipIfNoDirectIO(t, dir)
+
+	fp := filepath.Join(dir, "a.log")
+	r, err := New(&Config{
+		OutputPath:        fp,
+		MaxSizeBytes:      8192,
+		PerWriteSizeBytes: 4096,
+		PerSyncSizeBytes:  8192,
+		DirectIO:          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// Reading the log file after it's removed.
+	old, err := os.Open(fmt.Sprintf("/proc/self/fd/%d", r.f.Fd()))
+	if err != nil {
+		t.Skip("can't open the log file by fd:", err)
+	}
+	defer old.Close()
+
+	var exp []byte
+	write := func(i int) {
+		p := []byte(fmt.Sprintf("line-%04d-%s\n", i, bytes.Repeat([]byte{'x'}, 88)))
+		exp = append(exp, p...)
+		r.Write(p)
+	}
+	write(0)
+	waitWritten(t, r, int64(len(exp)))
+	err = os.Remove(fp) // Renaming will fail.
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; len(exp) < 8192*2; i++ {
+		write(i)
+		time.Sleep(2 * time.Millisecond) // Written by writeLoop, rotating by size.
+		if i%10 == 0 {
+			waitWritten(t, r, int64(len(exp))) // Flushing partial blocks.
+		}
+	}
+	waitWritten(t, r, int64(len(exp)))
+
+	act := make([]byte, len(exp))
+	_, err = old.ReadAt(act, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(act, exp) {
+		t.Fatal("content mismatch")
+	}
+	if bs, _ := listBackups(fp, 100); bs.Len() != 0 {
+		t.Fatal("should not rotate")
+	}
+}
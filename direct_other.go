@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+// oDirect is the flag of opening file for direct I/O, it's only supported on Linux.
+const oDirect = 0
+
+const directIOSupported = false
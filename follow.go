@@ -0,0 +1,479 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// FileID is the unique id of a file, it won't change after renaming.
+type FileID struct {
+	Dev uint64 `json:"dev"`
+	Ino uint64 `json:"ino"`
+}
+
+func getFileID(fi os.FileInfo) FileID {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}
+	}
+	return FileID{Dev: uint64(st.Dev), Ino: st.Ino}
+}
+
+func statFileID(fp string) (FileID, error) {
+	fi, err := os.Stat(fp)
+	if err != nil {
+		return FileID{}, err
+	}
+	return getFileID(fi), nil
+}
+
+// Checkpoint is the position of Follower,
+// it could be saved for resuming following.
+type Checkpoint struct {
+	FileID FileID `json:"file_id"`
+	// Offset is the offset of the next record in the file.
+	Offset int64 `json:"offset"`
+}
+
+// FollowOptions is the options of Follow.
+type FollowOptions struct {
+	ReadOptions
+	// From is the checkpoint to resume from.
+	// If it's nil or the file of it can't be found
+	// (e.g. removed or compressed), following will start at the beginning of the active log file.
+	From *Checkpoint
+	// FromEnd makes following start at the end of the active log file
+	// (only new records will be read) if From is nil.
+	FromEnd bool
+	// PollInterval is the interval of checking new data when reaching EOF.
+	// Default: 100ms.
+	PollInterval time.Duration
+}
+
+const defaultPollInterval = 100 * time.Millisecond
+
+// Follower reads records from logro-managed log files like `tail -F`.
+//
+// When the active log file is renamed by rotation,
+// Follower finishes the old one then continues at the next file.
+type Follower struct {
+	ctx        context.Context
+	outputPath string
+	opts       FollowOptions
+
+	f        *os.File
+	id       FileID
+	fp       string
+	splitter *recordSplitter
+	buf      []byte
+	start    int // Start of unread data in buf.
+	end      int // End of data in buf.
+
+	rec Record
+	err error
+}
+
+// Follow follows logro-managed log files of outputPath until ctx is done.
+func Follow(ctx context.Context, outputPath string, opts *FollowOptions) (fl *Follower, err error) {
+
+	fl = &Follower{
+		ctx:        ctx,
+		outputPath: outputPath,
+		buf:        make([]byte, 64*kb),
+	}
+	if opts != nil {
+		fl.opts = *opts
+	}
+	if fl.opts.PollInterval <= 0 {
+		fl.opts.PollInterval = defaultPollInterval
+	}
+
+	fp, offset := outputPath, int64(0)
+	if cp := fl.opts.From; cp != nil {
+		files, err := followFiles(outputPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.id == cp.FileID {
+				fp, offset = f.fp, cp.Offset
+				break
+			}
+		}
+	} else if fl.opts.FromEnd {
+		offset, err = tailBoundary(outputPath, fl.opts.ReadOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = fl.openFile(fp, offset)
+	if err != nil {
+		return nil, err
+	}
+	return fl, nil
+}
+
+// tailBoundary returns the end of the last complete record in the log file.
+func tailBoundary(fp string, opts ReadOptions) (int64, error) {
+
+	f, err := os.Open(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+	return fileTailBoundary(f, opts)
+}
+
+// fileTailBoundary is tailBoundary of the opened log file f.
+func fileTailBoundary(f *os.File, opts ReadOptions) (int64, error) {
+
+	size, err := writtenSize(f, opts.DirectIO)
+	if err != nil {
+		return 0, err
+	}
+	window := 64 * kb
+	if window > size {
+		window = size
+	}
+	tail := make([]byte, window)
+	_, err = f.ReadAt(tail, size-window)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	boundary := lastBoundary
+	if opts.Framed {
+		boundary = lastFrameBoundary
+	}
+	return size - window + int64(boundary(tail)), nil
+}
+
+type followFile struct {
+	fp string
+	id FileID
+}
+
+// followFiles returns uncompressed backups (from the oldest to the newest)
+// and the active log file (if it exists).
+//
+// Listing backups & the active log file isn't atomic,
+// it's retried if the log file is rotated in the middle, avoiding missing a rotated file.
+func followFiles(outputPath string) (files []followFile, err error) {
+
+	for {
+		before, berr := statFileID(outputPath)
+
+		bs, err := scanBackups(outputPath)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, b := range bs.sorted() {
+			if isCompressed(b.fp) {
+				continue
+			}
+			id, err := statFileID(b.fp)
+			if err != nil {
+				continue // Removed by rotation.
+			}
+			files = append(files, followFile{b.fp, id})
+		}
+
+		after, aerr := statFileID(outputPath)
+		if (berr == nil) != (aerr == nil) || before != after {
+			continue // Rotated while listing.
+		}
+		if aerr == nil {
+			files = append(files, followFile{outputPath, after})
+		}
+		return files, nil
+	}
+}
+
+// openFile opens fp at offset, it will wait until fp is created.
+func (fl *Follower) openFile(fp string, offset int64) (err error) {
+
+	var f *os.File
+	for {
+		f, err = os.Open(fp)
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if !fl.wait() {
+			return fl.ctx.Err()
+		}
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if offset > fi.Size() { // Truncated.
+		offset = 0
+	}
+	if offset > 0 {
+		_, err = f.Seek(offset, io.SeekStart)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	fl.f = f
+	fl.id = getFileID(fi)
+	fl.fp = fp
+	fl.splitter = &recordSplitter{framed: fl.opts.Framed, off: offset}
+	fl.start, fl.end = 0, 0
+	return nil
+}
+
+// wait waits for PollInterval, returns false if ctx is done.
+func (fl *Follower) wait() bool {
+	t := time.NewTimer(fl.opts.PollInterval)
+	defer t.Stop()
+	select {
+	case <-fl.ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Next advances the Follower to the next record, it blocks until there is a record.
+// It returns false when ctx is done or an error happened.
+func (fl *Follower) Next() bool {
+
+	if fl.err != nil {
+		return false
+	}
+
+	atEOF := false // Current file is retired and read to EOF.
+	for {
+		advance, token, _ := fl.splitter.split(fl.buf[fl.start:fl.end], atEOF)
+		fl.start += advance
+		if token != nil {
+			fl.rec = fl.splitter.record(token, fl.fp)
+			return true
+		}
+
+		if atEOF {
+			fl.err = fl.next()
+			if fl.err != nil {
+				return false
+			}
+			atEOF = false
+			continue
+		}
+
+		n, err := fl.read(false)
+		if err != nil && err != io.EOF {
+			fl.err = err
+			return false
+		}
+		if n > 0 {
+			continue
+		}
+
+		retired, err := fl.isRetired()
+		if err != nil {
+			fl.err = err
+			return false
+		}
+		if retired {
+			// There may be data written before renaming.
+			for {
+				n, err = fl.read(true)
+				if err != nil && err != io.EOF {
+					fl.err = err
+					return false
+				}
+				if n == 0 {
+					break
+				}
+			}
+			atEOF = true
+			continue
+		}
+
+		err = fl.checkTruncated()
+		if err != nil {
+			fl.err = err
+			return false
+		}
+
+		if !fl.wait() {
+			fl.err = fl.ctx.Err()
+			return false
+		}
+	}
+}
+
+// checkTruncated reads the active log file from the beginning if it's truncated
+// (e.g. restarting logro in StartupTruncate mode).
+func (fl *Follower) checkTruncated() error {
+
+	fi, err := fl.f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() >= fl.splitter.off+int64(fl.end-fl.start) {
+		return nil
+	}
+	_, err = fl.f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	fl.splitter = &recordSplitter{framed: fl.opts.Framed}
+	fl.start, fl.end = 0, 0
+	return nil
+}
+
+// read reads data from file into buf.
+// In direct I/O mode, it only reads the written data if current file isn't retired (see writtenSize).
+func (fl *Follower) read(retired bool) (n int, err error) {
+
+	if fl.start > 0 {
+		copy(fl.buf, fl.buf[fl.start:fl.end])
+		fl.end -= fl.start
+		fl.start = 0
+	}
+	if fl.end == len(fl.buf) {
+		if len(fl.buf) >= maxRecordSize {
+			return 0, fmt.Errorf("failed to read %s: %s", fl.fp, bufio.ErrTooLong.Error())
+		}
+		size := len(fl.buf) * 2
+		if size > maxRecordSize {
+			size = maxRecordSize
+		}
+		buf := make([]byte, size)
+		copy(buf, fl.buf[:fl.end])
+		fl.buf = buf
+	}
+
+	p := fl.buf[fl.end:]
+	if fl.opts.DirectIO && !retired {
+		size, err := writtenSize(fl.f, true)
+		if err != nil {
+			return 0, err
+		}
+		left := size - (fl.splitter.off + int64(fl.end))
+		if left <= 0 {
+			return 0, nil
+		}
+		if int64(len(p)) > left {
+			p = p[:left]
+		}
+	}
+	n, err = fl.f.Read(p)
+	fl.end += n
+	return
+}
+
+// isRetired returns true if current file isn't the active log file anymore.
+func (fl *Follower) isRetired() (bool, error) {
+
+	if fl.fp != fl.outputPath { // Backup.
+		return true, nil
+	}
+	id, err := statFileID(fl.outputPath)
+	if err != nil {
+		if os.IsNotExist(err) { // Creating new file in rotation.
+			return false, nil
+		}
+		return false, err
+	}
+	return id != fl.id, nil
+}
+
+// next closes current file and opens the next one.
+//
+// The next one is the file after current file in followFiles,
+// it waits if there is no such file yet (the active log file hasn't been created in rotation),
+// but never jumps to the active log file, which may skip rotated files.
+// If current file can't be found (e.g. removed or compressed),
+// it opens the oldest one modified after current file.
+func (fl *Follower) next() error {
+
+	cur, err := fl.f.Stat()
+	if err != nil {
+		return err
+	}
+	for {
+		files, err := followFiles(fl.outputPath)
+		if err != nil {
+			return err
+		}
+		fp, found := "", false
+		for i, f := range files {
+			if f.id == fl.id {
+				found = true
+				if i+1 < len(files) {
+					fp = files[i+1].fp
+				}
+				break
+			}
+		}
+		if !found {
+			for _, f := range files {
+				fi, err := os.Stat(f.fp)
+				if err == nil && !fi.ModTime().Before(cur.ModTime()) {
+					fp = f.fp
+					break
+				}
+			}
+		}
+		if fp != "" {
+			fl.f.Close()
+			return fl.openFile(fp, 0)
+		}
+		if !fl.wait() {
+			return fl.ctx.Err()
+		}
+	}
+}
+
+// Record returns the current record.
+func (fl *Follower) Record() Record {
+	return fl.rec
+}
+
+// Checkpoint returns the position after the current record.
+func (fl *Follower) Checkpoint() Checkpoint {
+	return Checkpoint{
+		FileID: fl.id,
+		Offset: fl.splitter.off,
+	}
+}
+
+// Err returns the error that was encountered by the Follower,
+// it's ctx.Err() if ctx is done.
+func (fl *Follower) Err() error {
+	return fl.err
+}
+
+// Close closes the Follower.
+func (fl *Follower) Close() error {
+	if fl.f != nil {
+		return fl.f.Close()
+	}
+	return nil
+}
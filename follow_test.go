@@ -0,0 +1,257 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func followN(t *testing.T, fl *Follower, n int) (records [][]byte) {
+	for len(records) < n && fl.Next() {
+		records = append(records, append([]byte(nil), fl.Record().Data...))
+	}
+	if fl.Err() != nil {
+		t.Fatal(fl.Err(), len(records))
+	}
+	return
+}
+
+func TestFollow(t *testing.T) {
+	for _, framed := range []bool{false, true} {
+		testFollow(t, framed)
+	}
+}
+
+func testFollow(t *testing.T, framed bool) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	r, err := New(&Config{OutputPath: output, MaxSize: 64, MaxBackups: 64, PerWriteSize: 16, PerSyncSize: 32,
+		Developed: true, Framed: framed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fl, err := Follow(ctx, output, &FollowOptions{
+		ReadOptions:  ReadOptions{Framed: framed},
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	var exp [][]byte
+	go func() {
+		for i := 0; i < 64; i++ {
+			p := []byte(fmt.Sprintf("record-%02d", i))
+			if !framed {
+				p = append(p, '\n')
+			}
+			r.Write(p)
+			if i%8 == 0 {
+				r.Sync()
+			}
+			time.Sleep(time.Millisecond)
+		}
+		r.Sync()
+	}()
+	for i := 0; i < 64; i++ {
+		exp = append(exp, []byte(fmt.Sprintf("record-%02d", i)))
+	}
+
+	act := followN(t, fl, 48)
+	cp := fl.Checkpoint()
+	fl.Close()
+
+	fl, err = Follow(ctx, output, &FollowOptions{
+		ReadOptions:  ReadOptions{Framed: framed},
+		PollInterval: time.Millisecond,
+		From:         &cp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	act = append(act, followN(t, fl, 16)...)
+
+	if !isMatchRecords(exp, act) {
+		t.Fatalf("records mismatch: %q", act)
+	}
+}
+
+func TestFollowCancel(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	err = ioutil.WriteFile(output, []byte("a\nb"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fl, err := Follow(ctx, output, &FollowOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	if !fl.Next() || string(fl.Record().Data) != "a" {
+		t.Fatal("should read the complete line")
+	}
+	cancel()
+	if fl.Next() {
+		t.Fatal("should not return the partial line")
+	}
+	if fl.Err() != context.Canceled {
+		t.Fatal("mismatch error", fl.Err())
+	}
+	if cp := fl.Checkpoint(); cp.Offset != 2 {
+		t.Fatal("mismatch checkpoint", cp.Offset)
+	}
+}
+
+func TestFollowTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	err = ioutil.WriteFile(output, []byte("aaaa\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fl, err := Follow(ctx, output, &FollowOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	act := followN(t, fl, 1)
+	err = ioutil.WriteFile(output, []byte("b\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act = append(act, followN(t, fl, 1)...)
+	if !isMatchRecords([][]byte{[]byte("aaaa"), []byte("b")}, act) {
+		t.Fatalf("records mismatch: %q", act)
+	}
+}
+
+func TestFollowFromEnd(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	err = ioutil.WriteFile(output, []byte("a\nb"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fl, err := Follow(ctx, output, &FollowOptions{PollInterval: time.Millisecond, FromEnd: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("c\n"))
+	f.Close()
+
+	act := followN(t, fl, 1)
+	if string(act[0]) != "bc" {
+		t.Fatalf("should start at the last complete record: %q", act[0])
+	}
+}
+
+// Two rotations happen between next() calls:
+// the active log file is missing when current file is retired,
+// then the next one is created, rotated & replaced quickly.
+func TestFollowQuickRotations(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	err = ioutil.WriteFile(output, []byte("a\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fl, err := Follow(ctx, output, &FollowOptions{PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+	act := followN(t, fl, 1)
+
+	now := time.Now()
+	backup1, _ := makeBackupFP(output, false, now)
+	backup2, _ := makeBackupFP(output, false, now.Add(time.Second))
+	err = os.Rename(output, backup1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fl.next() // Waiting for the next file.
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	for _, step := range []func() error{
+		func() error { return ioutil.WriteFile(output, []byte("b\n"), 0644) },
+		func() error { return os.Rename(output, backup2) },
+		func() error { return ioutil.WriteFile(output, []byte("c\n"), 0644) },
+	} {
+		if err = step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	act = append(act, followN(t, fl, 2)...)
+	if !isMatchRecords([][]byte{[]byte("a"), []byte("b"), []byte("c")}, act) {
+		t.Fatalf("records mismatch: %q", act)
+	}
+}
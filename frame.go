@@ -0,0 +1,261 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// Framed record layout:
+//
+//	+-------+-------+--------+--------+------------+---------+
+//	| magic | flags | length | crc32c | ext fields | payload |
+//	+-------+-------+--------+--------+------------+---------+
+//	|   3   |   1   |   4    |   4    | by flags   | length  |
+//	+-------+-------+--------+--------+------------+---------+
+//
+// length & crc32c & ext fields are little endian,
+// crc32c is the checksum of flags & ext fields & payload.
+//
+// ext fields (in order):
+//
+//	time: 8 bytes, Unix nanoseconds, exists if flags&frameFlagTime != 0.
+//	seq:  8 bytes, sequence number, exists if flags&frameFlagSeq != 0.
+const (
+	frameHeaderSize = 12
+
+	frameFlagTime  byte = 1 << 0
+	frameFlagSeq   byte = 1 << 1
+	frameFlagsMask      = frameFlagTime | frameFlagSeq
+
+	maxFrameHeaderSize = frameHeaderSize + 8 + 8
+
+	// MaxFramePayload is the max payload size of a framed record,
+	// larger records will be dropped in framed mode.
+	MaxFramePayload = 16 * mb
+)
+
+var frameMagic = []byte{0xa5, 'L', 'R'}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// frameMeta is the flags & ext fields of a frame.
+type frameMeta struct {
+	flags byte
+	ts    int64
+	seq   uint64
+}
+
+// extSize returns the size of ext fields.
+func extSize(flags byte) int {
+	n := 0
+	if flags&frameFlagTime != 0 {
+		n += 8
+	}
+	if flags&frameFlagSeq != 0 {
+		n += 8
+	}
+	return n
+}
+
+// putFrameHeader puts the frame header of payload p into hdr,
+// returns the header size.
+// hdr must be longer than maxFrameHeaderSize.
+func putFrameHeader(hdr []byte, m frameMeta, p []byte) int {
+	copy(hdr, frameMagic)
+	hdr[3] = m.flags
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(len(p)))
+	n := frameHeaderSize
+	if m.flags&frameFlagTime != 0 {
+		binary.LittleEndian.PutUint64(hdr[n:], uint64(m.ts))
+		n += 8
+	}
+	if m.flags&frameFlagSeq != 0 {
+		binary.LittleEndian.PutUint64(hdr[n:], m.seq)
+		n += 8
+	}
+	binary.LittleEndian.PutUint32(hdr[8:12], frameChecksum(hdr[3:4], hdr[frameHeaderSize:n], p))
+	return n
+}
+
+// appendFrame appends framed p to dst.
+func appendFrame(dst []byte, m frameMeta, p []byte) []byte {
+	var hdr [maxFrameHeaderSize]byte
+	n := putFrameHeader(hdr[:], m, p)
+	dst = append(dst, hdr[:n]...)
+	return append(dst, p...)
+}
+
+func frameChecksum(flags, ext, p []byte) uint32 {
+	crc := crc32.Checksum(flags, crc32cTable)
+	crc = crc32.Update(crc, crc32cTable, ext)
+	return crc32.Update(crc, crc32cTable, p)
+}
+
+// parseFrame parses a valid frame.
+func parseFrame(frame []byte) (m frameMeta, payload []byte) {
+	m.flags = frame[3]
+	n := frameHeaderSize
+	if m.flags&frameFlagTime != 0 {
+		m.ts = int64(binary.LittleEndian.Uint64(frame[n:]))
+		n += 8
+	}
+	if m.flags&frameFlagSeq != 0 {
+		m.seq = binary.LittleEndian.Uint64(frame[n:])
+		n += 8
+	}
+	return m, frame[n:]
+}
+
+// frameSplitter is a bufio.SplitFunc maker which splits framed records,
+// corrupt regions will be skipped.
+type frameSplitter struct {
+	skipped int64
+}
+
+// split returns the whole frame as token,
+// corrupt bytes before the frame are skipped (counted in advance).
+//
+// bufio.Scanner stops if there is no token after EOF,
+// so skipping happens in one call as much as possible.
+func (s *frameSplitter) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+
+	for advance < len(data) {
+		n, token := nextFrame(data[advance:], atEOF)
+		if token != nil {
+			return advance + len(token), token, nil
+		}
+		if n == 0 { // Need more data.
+			return advance, nil, nil
+		}
+		advance += n
+		s.skipped += int64(n)
+	}
+	return advance, nil, nil
+}
+
+// nextFrame returns the frame at the beginning of data,
+// or the number of corrupt bytes should be skipped.
+// If both are zero, more data is needed.
+func nextFrame(data []byte, atEOF bool) (skip int, frame []byte) {
+
+	i := bytes.Index(data, frameMagic)
+	if i < 0 {
+		if atEOF {
+			return len(data), nil
+		}
+		// The magic may be cut off, keeping the last bytes.
+		n := len(data) - (len(frameMagic) - 1)
+		if n <= 0 {
+			return 0, nil
+		}
+		return n, nil
+	}
+	if i > 0 {
+		return i, nil
+	}
+
+	if len(data) < frameHeaderSize {
+		if atEOF {
+			return len(data), nil
+		}
+		return 0, nil
+	}
+
+	flags := data[3]
+	n := int64(binary.LittleEndian.Uint32(data[4:8]))
+	if flags&^frameFlagsMask != 0 || n > MaxFramePayload {
+		return 1, nil
+	}
+	hs := frameHeaderSize + extSize(flags)
+	size := hs + int(n)
+	if len(data) < size {
+		if atEOF {
+			return 1, nil // Torn frame.
+		}
+		return 0, nil
+	}
+	if binary.LittleEndian.Uint32(data[8:12]) !=
+		frameChecksum(data[3:4], data[frameHeaderSize:hs], data[hs:size]) {
+		return 1, nil
+	}
+	return 0, data[:size]
+}
+
+// framePayload returns the payload of a valid frame.
+func framePayload(frame []byte) []byte {
+	_, p := parseFrame(frame)
+	return p
+}
+
+// lastFrameBoundary returns the end of the last valid frame in p,
+// if there is no valid frame, returns the end of the last non-zero byte.
+func lastFrameBoundary(p []byte) int {
+	s := new(frameSplitter)
+	off, end := 0, -1
+	for off < len(p) {
+		advance, token, _ := s.split(p[off:], true)
+		if advance == 0 {
+			break
+		}
+		off += advance
+		if token != nil {
+			end = off
+		}
+	}
+	if end >= 0 {
+		return end
+	}
+	return lastNonZero(p)
+}
+
+// FrameReader reads records written in framed mode.
+// Checksums will be validated, and corrupt regions will be skipped.
+type FrameReader struct {
+	sc       *bufio.Scanner
+	splitter *frameSplitter
+}
+
+// NewFrameReader creates a FrameReader reading from r.
+func NewFrameReader(r io.Reader) *FrameReader {
+	s := new(frameSplitter)
+	return &FrameReader{
+		sc:       newFrameScanner(r, s),
+		splitter: s,
+	}
+}
+
+func newFrameScanner(r io.Reader, s *frameSplitter) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*kb), maxFrameHeaderSize+int(MaxFramePayload))
+	sc.Split(s.split)
+	return sc
+}
+
+// Next returns the payload of the next valid record,
+// it returns io.EOF if there is no more record.
+//
+// The payload may be overwritten by the next call of Next.
+func (fr *FrameReader) Next() (payload []byte, err error) {
+	if !fr.sc.Scan() {
+		if err = fr.sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return framePayload(fr.sc.Bytes()), nil
+}
+
+// Skipped returns the number of bytes skipped because of corruption.
+func (fr *FrameReader) Skipped() int64 {
+	return fr.splitter.skipped
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func makeTestFrames(n int) (records [][]byte, framed []byte) {
+	for i := 0; i < n; i++ {
+		p := make([]byte, rand.Intn(256))
+		rand.Read(p)
+		records = append(records, p)
+		framed = appendFrame(framed, frameMeta{}, p)
+	}
+	return
+}
+
+func readAllFrames(t *testing.T, fr *FrameReader) (records [][]byte) {
+	for {
+		p, err := fr.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, append([]byte(nil), p...))
+	}
+}
+
+func isMatchRecords(exp, act [][]byte) bool {
+	if len(exp) != len(act) {
+		return false
+	}
+	for i := range exp {
+		if !bytes.Equal(exp[i], act[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFrameReader(t *testing.T) {
+	records, framed := makeTestFrames(128)
+
+	fr := NewFrameReader(bytes.NewReader(framed))
+	if !isMatchRecords(records, readAllFrames(t, fr)) {
+		t.Fatal("records mismatch")
+	}
+	if fr.Skipped() != 0 {
+		t.Fatal("should not skip")
+	}
+}
+
+func TestFrameReaderCorrupt(t *testing.T) {
+	records, _ := makeTestFrames(3)
+	records[1] = []byte("corrupted")
+
+	var p []byte
+	p = append(p, "garbage"...)
+	p = appendFrame(p, frameMeta{}, records[0])
+	mid := len(p)
+	p = appendFrame(p, frameMeta{}, records[1])
+	p[mid+frameHeaderSize] ^= 0xff // Flip payload.
+	p = append(p, frameMagic...)   // Broken header.
+	p = appendFrame(p, frameMeta{}, records[2])
+	p = append(p, appendFrame(nil, frameMeta{}, []byte("torn"))[:frameHeaderSize+2]...)
+
+	fr := NewFrameReader(bytes.NewReader(p))
+	act := readAllFrames(t, fr)
+	if !isMatchRecords([][]byte{records[0], records[2]}, act) {
+		t.Fatal("valid records mismatch")
+	}
+	expSkipped := len("garbage") + (frameHeaderSize + len(records[1])) + len(frameMagic) + frameHeaderSize + 2
+	if fr.Skipped() != int64(expSkipped) {
+		t.Fatal("mismatch skipped", fr.Skipped(), expSkipped)
+	}
+}
+
+func TestLastFrameBoundary(t *testing.T) {
+	_, framed := makeTestFrames(3)
+
+	if lastFrameBoundary(framed) != len(framed) {
+		t.Fatal("complete frames should be kept")
+	}
+	torn := append(append([]byte(nil), framed...), appendFrame(nil, frameMeta{}, []byte("torn"))[:5]...)
+	if lastFrameBoundary(torn) != len(framed) {
+		t.Fatal("torn frame should be discarded")
+	}
+	zeros := append(append([]byte(nil), framed...), make([]byte, 4096)...)
+	if lastFrameBoundary(zeros) != len(framed) {
+		t.Fatal("zeros should be discarded")
+	}
+	if lastFrameBoundary([]byte{1, 2, 0}) != 2 {
+		t.Fatal("only zeros should be discarded if there is no frame")
+	}
+}
+
+func TestRotation_WriteFramed(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "logro-test.log")
+	r, err := New(&Config{OutputPath: fp, MaxSize: 1 << 20, PerWriteSize: 64, PerSyncSize: 128,
+		Developed: true, Framed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	records, _ := makeTestFrames(64)
+	records = append(records, []byte("multi\nline\n"))
+	for _, p := range records {
+		r.Write(p)
+	}
+	r.Write(make([]byte, MaxFramePayload+1))
+	r.Sync()
+	time.Sleep(10 * time.Millisecond)
+
+	f, err := os.Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if !isMatchRecords(records, readAllFrames(t, NewFrameReader(f))) {
+		t.Fatal("records mismatch")
+	}
+	if r.Stats().Oversized != 1 {
+		t.Fatal("oversized record should be dropped")
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	for _, m := range []frameMeta{
+		{},
+		{flags: frameFlagTime, ts: 1},
+		{flags: frameFlagSeq, seq: 2},
+		{flags: frameFlagTime | frameFlagSeq, ts: 3, seq: 4},
+	} {
+		frame := appendFrame(nil, m, []byte("abc"))
+		_, token := nextFrame(frame, true)
+		if token == nil {
+			t.Fatal("should be a valid frame", m)
+		}
+		m2, p := parseFrame(token)
+		if m2 != m || string(p) != "abc" {
+			t.Fatal("mismatch frame", m, m2)
+		}
+	}
+}
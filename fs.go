@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"os"
+
+	"github.com/templexxx/fnc"
+)
+
+// fileSystem is the page cache control of log files used by syncer,
+// it could be instrumented in testing.
+type fileSystem interface {
+	// FlushHint starts writeback of dirty pages in range (without waiting).
+	FlushHint(f *os.File, offset, size int64) error
+	// DropCache drops clean pages in range from page cache.
+	DropCache(f *os.File, offset, size int64) error
+	// Fdatasync flushes data of f to disk (waiting for it).
+	Fdatasync(f *os.File) error
+}
+
+// osFS is the fileSystem of OS.
+type osFS struct{}
+
+func (osFS) FlushHint(f *os.File, offset, size int64) error {
+	return fnc.FlushHint(f, offset, size)
+}
+
+func (osFS) DropCache(f *os.File, offset, size int64) error {
+	return fnc.DropCache(f, offset, size)
+}
+
+func (osFS) Fdatasync(f *os.File) error {
+	return fdatasync(f)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import "os"
+
+// fdatasync falls back to fsync on platforms without fdatasync.
+func fdatasync(f *os.File) error {
+	return f.Sync()
+}
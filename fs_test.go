@@ -0,0 +1,483 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fsCall is a call of fileSystem.
+type fsCall struct {
+	op           string
+	offset, size int64
+}
+
+// recordFS is an instrumented fileSystem recording calls.
+type recordFS struct {
+	mu    sync.Mutex
+	calls []fsCall
+	// syncErr is returned by Fdatasync.
+	syncErr error
+}
+
+func (fs *recordFS) record(op string, offset, size int64) {
+	fs.mu.Lock()
+	fs.calls = append(fs.calls, fsCall{op, offset, size})
+	fs.mu.Unlock()
+}
+
+func (fs *recordFS) FlushHint(f *os.File, offset, size int64) error {
+	fs.record("flush", offset, size)
+	return nil
+}
+
+func (fs *recordFS) DropCache(f *os.File, offset, size int64) error {
+	fs.record("drop", offset, size)
+	return nil
+}
+
+func (fs *recordFS) Fdatasync(f *os.File) error {
+	fs.record("fdatasync", 0, 0)
+	return fs.syncErr
+}
+
+// all returns all calls.
+func (fs *recordFS) all() []fsCall {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]fsCall(nil), fs.calls...)
+}
+
+// get returns calls of op.
+func (fs *recordFS) get(op string) (calls []fsCall) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, c := range fs.calls {
+		if c.op == op {
+			calls = append(calls, c)
+		}
+	}
+	return
+}
+
+// newTestFSRotation creates a Rotation with fs.
+func newTestFSRotation(t *testing.T, cfg *Config, fs fileSystem) *Rotation {
+	r, err := prepare(cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.fs = fs
+	r.run()
+	return r
+}
+
+func TestRotation_CachePolicy(t *testing.T) {
+
+	const maxSize = 64 * 1024
+	for _, policy := range []CachePolicy{"", CacheDropRotated, CacheDropBehind, CacheKeepRotated, CacheKernel} {
+		t.Run(string(policy), func(t *testing.T) {
+			dir, err := ioutil.TempDir(os.TempDir(), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			fs := new(recordFS)
+			fp := filepath.Join(dir, "a.log")
+			r := newTestFSRotation(t, &Config{
+				OutputPath:        fp,
+				MaxSizeBytes:      maxSize,
+				BufItem:           4096,
+				PerWriteSizeBytes: 4096,
+				PerSyncSizeBytes:  8192,
+				CachePolicy:       policy,
+			}, fs)
+			defer r.Close()
+
+			p := bytes.Repeat([]byte{'x'}, 1000)
+			for i := 0; i < 200; i++ {
+				r.Write(p)
+				if i%10 == 0 {
+					time.Sleep(time.Millisecond) // Written by writeLoop, rotating by size.
+				}
+			}
+			waitBackups(t, fp, 2)
+
+			// Waiting for syncer.
+			dropping := policy != CacheKeepRotated && policy != CacheKernel
+			for i := 0; i < 100; i++ {
+				if len(fs.get("flush")) > 0 && (!dropping || len(fs.get("drop")) > 0) {
+					break
+				}
+				if policy == CacheKernel && len(fs.get("fdatasync")) > 0 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			flushes, drops, calls := fs.get("flush"), fs.get("drop"), fs.all()
+			var retired, behind int
+			for _, c := range drops {
+				if c.offset == 0 && c.size >= maxSize { // Retired files end with a complete record.
+					retired++
+				} else {
+					behind++
+				}
+			}
+
+			switch policy {
+			case "", CacheDropRotated:
+				if len(flushes) == 0 || retired == 0 || behind != 0 {
+					t.Fatal("mismatch", calls)
+				}
+			case CacheDropBehind:
+				if len(flushes) == 0 || retired == 0 || behind == 0 {
+					t.Fatal("mismatch", calls)
+				}
+				// Dropped ranges are behind flushed ones in a file.
+				var flushed, dropped int64
+				for _, c := range calls {
+					switch {
+					case c.op == "fdatasync":
+					case c.op == "flush" && c.size >= maxSize: // Retired.
+						flushed, dropped = 0, 0
+					case c.op == "flush":
+						if c.offset != flushed {
+							t.Fatal("flush range mismatch", calls)
+						}
+						flushed += c.size
+					case c.size < maxSize:
+						if c.offset != dropped || c.offset+c.size > flushed {
+							t.Fatal("drop range mismatch", calls)
+						}
+						dropped += c.size
+					}
+				}
+			case CacheKeepRotated:
+				if len(flushes) == 0 || len(drops) != 0 {
+					t.Fatal("mismatch", calls)
+				}
+			case CacheKernel:
+				if len(flushes) != 0 || len(drops) != 0 || len(fs.get("fdatasync")) == 0 || r.Stats().Syncs != 0 {
+					t.Fatal("mismatch", calls)
+				}
+			}
+		})
+	}
+}
+
+// waitRetired waits for n retired log files synced by syncer.
+func waitRetired(t *testing.T, fs *recordFS, n int) {
+	for i := 0; i < 100; i++ {
+		if len(fs.get("fdatasync")) >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("retired log file should be synced")
+}
+
+// checkRetired checks the retired log file (the last one) is flushed, synced & dropped in [0, size).
+func checkRetired(t *testing.T, fs *recordFS, size int64) {
+
+	calls := fs.all()
+	synced := -1
+	for i, c := range calls {
+		if c.op == "fdatasync" {
+			synced = i
+		}
+	}
+	if synced < 1 || synced+1 >= len(calls) {
+		t.Fatal("mismatch", calls)
+	}
+	flush, drop := calls[synced-1], calls[synced+1]
+	if flush != (fsCall{"flush", 0, size}) || drop != (fsCall{"drop", 0, size}) {
+		t.Fatal("retired range mismatch", size, calls)
+	}
+}
+
+func TestRotation_RetireLength(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const maxSize = 64 * 1024
+	makeCfg := func(fp string) *Config {
+		return &Config{
+			OutputPath:        fp,
+			MaxSizeBytes:      maxSize,
+			BufItem:           4096,
+			PerWriteSizeBytes: 4096,
+			PerSyncSizeBytes:  8192,
+		}
+	}
+	fs := new(recordFS)
+	fp := filepath.Join(dir, "a.log")
+	r := newTestFSRotation(t, makeCfg(fp), fs)
+	defer r.Close()
+
+	// Larger than MaxSize: the last record is written to the old file.
+	p := bytes.Repeat([]byte{'x'}, 5000)
+	n := maxSize/len(p) + 1
+	size := int64(n * len(p))
+	for i := 0; i < n; i++ {
+		r.Write(p)
+		time.Sleep(time.Millisecond)
+	}
+	waitBackups(t, fp, 1)
+	waitRetired(t, fs, 1)
+	checkRetired(t, fs, size)
+
+	bs, err := filepath.Glob(filepath.Join(dir, "a-*.log"))
+	if err != nil || len(bs) != 1 {
+		t.Fatal("should have a backup", bs, err)
+	}
+	fi, err := os.Stat(bs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != size {
+		t.Fatal("backup size mismatch", fi.Size())
+	}
+
+	// Smaller than MaxSize: switching OutputPath.
+	p = bytes.Repeat([]byte{'y'}, 100)
+	for i := 0; i < 10; i++ {
+		r.Write(p)
+	}
+	waitWritten(t, r, size+1000)
+	err = r.UpdateConfig(makeCfg(filepath.Join(dir, "b.log")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitRetired(t, fs, 2)
+	checkRetired(t, fs, 1000)
+}
+
+func TestRotation_RetireError(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := &recordFS{syncErr: errors.New("injected")}
+	r := newTestFSRotation(t, &Config{OutputPath: filepath.Join(dir, "a.log")}, fs)
+	defer r.Close()
+
+	r.Write([]byte("a"))
+	waitWritten(t, r, 1)
+	err = r.UpdateConfig(&Config{OutputPath: filepath.Join(dir, "b.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitRetired(t, fs, 1)
+	for i := 0; i < 100; i++ {
+		if r.Stats().FlushErrors == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("flush error should be counted", r.Stats().FlushErrors)
+}
+
+func TestRotation_AppendFlushRange(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "a.log")
+	const existed = 100000
+	err = ioutil.WriteFile(fp, append(bytes.Repeat([]byte{'x'}, existed-1), '\n'), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := new(recordFS)
+	r := newTestFSRotation(t, &Config{
+		OutputPath:        fp,
+		MaxSizeBytes:      1024 * 1024,
+		BufItem:           4096,
+		PerWriteSizeBytes: 4096,
+		PerSyncSizeBytes:  8192,
+		CachePolicy:       CacheDropBehind,
+		StartupMode:       StartupAppend,
+	}, fs)
+	defer r.Close()
+
+	p := append(bytes.Repeat([]byte{'y'}, 999), '\n')
+	for i := 0; i < 40; i++ {
+		r.Write(p)
+		if i%10 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if len(fs.get("drop")) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	flushes, drops := fs.get("flush"), fs.get("drop")
+	if len(flushes) < 2 || len(drops) == 0 {
+		t.Fatal("mismatch", fs.all())
+	}
+	// Only the appended data is flushed & dropped.
+	if flushes[0].offset != existed || drops[0].offset != existed {
+		t.Fatal("range mismatch", fs.all())
+	}
+}
+
+func TestRotation_SwitchAppendFlushRange(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const existed = 50000
+	bfp := filepath.Join(dir, "b.log")
+	err = ioutil.WriteFile(bfp, append(bytes.Repeat([]byte{'x'}, existed-1), '\n'), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	makeCfg := func(fp string) *Config {
+		return &Config{
+			OutputPath:        fp,
+			MaxSizeBytes:      1024 * 1024,
+			BufItem:           4096,
+			PerWriteSizeBytes: 4096,
+			PerSyncSizeBytes:  8192,
+			CachePolicy:       CacheDropBehind,
+			StartupMode:       StartupAppend,
+		}
+	}
+	fs := new(recordFS)
+	r := newTestFSRotation(t, makeCfg(filepath.Join(dir, "a.log")), fs)
+	defer r.Close()
+
+	r.Write([]byte("a\n"))
+	waitWritten(t, r, 2)
+	err = r.UpdateConfig(makeCfg(bfp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitRetired(t, fs, 1)
+
+	p := append(bytes.Repeat([]byte{'y'}, 999), '\n')
+	for i := 0; i < 40; i++ {
+		r.Write(p)
+		if i%10 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if len(fs.get("drop")) > 1 { // The retired one & the behind one.
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Calls after retiring a.log.
+	calls := fs.all()
+	for i, c := range calls {
+		if c.op == "fdatasync" {
+			calls = calls[i+2:] // Skipping fdatasync & dropping of a.log.
+			break
+		}
+	}
+	var flushes, drops []fsCall
+	for _, c := range calls {
+		if c.op == "flush" {
+			flushes = append(flushes, c)
+		} else if c.op == "drop" {
+			drops = append(drops, c)
+		}
+	}
+	if len(flushes) < 2 || len(drops) == 0 {
+		t.Fatal("mismatch", fs.all())
+	}
+	// Only the appended data of b.log is flushed & dropped.
+	if flushes[0].offset != existed || drops[0].offset != existed {
+		t.Fatal("range mismatch", fs.all())
+	}
+}
+
+// blockFS blocks FlushHint until block is closed.
+type blockFS struct {
+	*recordFS
+	block chan struct{}
+}
+
+func (fs *blockFS) FlushHint(f *os.File, offset, size int64) error {
+	fs.recordFS.FlushHint(f, offset, size)
+	<-fs.block
+	return nil
+}
+
+// The retired log files left in queue are synced & closed by Close.
+func TestRotation_CloseRetire(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	makeCfg := func(name string) *Config {
+		return &Config{
+			OutputPath:        filepath.Join(dir, name),
+			BufItem:           4096,
+			PerWriteSizeBytes: 4096,
+			PerSyncSizeBytes:  8192,
+		}
+	}
+	fs := &blockFS{recordFS: new(recordFS), block: make(chan struct{})}
+	r := newTestFSRotation(t, makeCfg("a.log"), fs)
+
+	r.Write(bytes.Repeat([]byte{'x'}, 10000))
+	for i := 0; i < 100 && len(fs.get("flush")) == 0; i++ { // Syncer is blocked.
+		time.Sleep(10 * time.Millisecond)
+	}
+	names := []string{"b.log", "c.log", "d.log"}
+	for _, name := range names { // Retiring a.log, b.log & c.log.
+		err = r.UpdateConfig(makeCfg(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	go func() {
+		<-r.loopCtx.Done()
+		close(fs.block)
+	}()
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fs.get("fdatasync")); n != len(names) {
+		t.Fatal("retired log files should be synced", n, fs.all())
+	}
+}
@@ -3,8 +3,10 @@ module github.com/templexxx/logro
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/templexxx/fnc v1.0.0
 	github.com/templexxx/go-diodes v0.0.2
 	go.uber.org/goleak v1.0.0
+	go.uber.org/zap v1.16.0
 	golang.org/x/tools v0.0.0-20200403190813-44a64ad78b9b // indirect
 )
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// EnvPrefix is the prefix of environment variables overriding Config in LoadConfig.
+const EnvPrefix = "LOGRO_"
+
+// LoadConfig loads Config from the config file at path (JSON or TOML, by the extension),
+// then overrides it by environment variables.
+// If path is empty, only environment variables are loaded.
+//
+// The name of environment variable is EnvPrefix + the upper-cased key in config file,
+// e.g. LOGRO_OUTPUT_PATH, LOGRO_MAX_SIZE_MB=256 or LOGRO_MAX_SIZE_MB=256MiB.
+// It returns an error if there is any unknown variable with EnvPrefix (e.g. LOGRO_MAX_SIZE).
+//
+// The precedence: environment variables > config file > defaults (filled by New).
+// The Config is validated (unless Lenient), it's ready for New.
+func LoadConfig(path string) (cfg *Config, err error) {
+
+	cfg = new(Config)
+	if path != "" {
+		err = loadConfigFile(path, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = loadConfigEnv(os.Environ(), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Lenient {
+		err = cfg.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+func loadConfigFile(path string, cfg *Config) error {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %s", err.Error())
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, cfg)
+	case ".toml":
+		_, err = toml.Decode(string(data), cfg)
+	default:
+		return fmt.Errorf("unknown config file format: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %s", path, err.Error())
+	}
+	return nil
+}
+
+// loadConfigEnv overrides cfg by environment variables in env (key=value).
+func loadConfigEnv(env []string, cfg *Config) error {
+
+	vars := make(map[string]string)
+	for _, kv := range env {
+		i := strings.IndexByte(kv, '=')
+		if i > 0 && strings.HasPrefix(kv[:i], EnvPrefix) {
+			vars[kv[:i]] = kv[i+1:]
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+
+	// Developed changes the units of sizes (see Config.UnmarshalJSON), it's applied first.
+	t := reflect.TypeOf(*cfg)
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Name == "Developed" {
+			fields = append([]reflect.StructField{f}, fields...)
+		} else {
+			fields = append(fields, f)
+		}
+	}
+
+	for _, f := range fields {
+		key := strings.Split(f.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := EnvPrefix + strings.ToUpper(key)
+		v, ok := vars[name]
+		if !ok {
+			continue
+		}
+		delete(vars, name)
+
+		// Unmarshal as JSON, so sizes & durations are parsed as the same as config file.
+		raw := []byte(v)
+		if f.Type.Kind() == reflect.String || !json.Valid(raw) {
+			raw, _ = json.Marshal(v)
+		}
+		data := make([]byte, 0, len(key)+len(raw)+8)
+		data = append(data, `{"`...)
+		data = append(data, key...)
+		data = append(data, `":`...)
+		data = append(data, raw...)
+		data = append(data, '}')
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("illegal %s: %s", name, err.Error())
+		}
+	}
+
+	// Typos (e.g. LOGRO_MAX_SIZE for LOGRO_MAX_SIZE_MB) shouldn't be ignored silently.
+	unknown := make([]string, 0, len(vars))
+	for name := range vars {
+		unknown = append(unknown, name)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown environment variables: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
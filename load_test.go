@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "a.log")
+	files := map[string]string{
+		"logro.json": `{"output_path": "` + fp + `", "max_size_mb": "256MiB", "max_backups": 8,
+			"per_write_size": 128, "lock_wait": "1s", "startup_mode": "append", "framed": true}`,
+		"logro.toml": `output_path = "` + fp + `"
+max_size_mb = "256MiB"
+max_backups = 8
+per_write_size = 128
+lock_wait = "1s"
+startup_mode = "append"
+framed = true
+`,
+	}
+	exp := Config{OutputPath: fp, MaxSize: 256, MaxBackups: 8, PerWriteSize: 128,
+		LockWait: time.Second, StartupMode: StartupAppend, Framed: true}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if *cfg != exp {
+			t.Fatalf("%s mismatch, exp: %+v, got: %+v", name, exp, *cfg)
+		}
+	}
+
+	_, err = LoadConfig(filepath.Join(dir, "logro.yaml"))
+	if err == nil {
+		t.Fatal("should fail on missing file")
+	}
+	yp := filepath.Join(dir, "logro.yaml")
+	err = ioutil.WriteFile(yp, []byte("output_path: a.log"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(yp)
+	if err == nil {
+		t.Fatal("should fail on unknown format")
+	}
+
+	// Validated.
+	bp := filepath.Join(dir, "bad.json")
+	err = ioutil.WriteFile(bp, []byte(`{"output_path": "`+fp+`", "max_backups": -1}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(bp)
+	var ce *ConfigError
+	if !errors.As(err, &ce) {
+		t.Fatalf("should be ConfigError, got: %v", err)
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+
+	cfg := &Config{OutputPath: "a.log", MaxSize: 128, MaxBackups: 4, Framed: true}
+	err := loadConfigEnv([]string{
+		"HOME=/root",
+		"LOGRO_OUTPUT_PATH=b.log",
+		"LOGRO_MAX_SIZE_MB=1GiB",
+		"LOGRO_MAX_BACKUPS=9",
+		"LOGRO_FRAMED=false",
+		"LOGRO_LOCK_WAIT=2m",
+		"LOGRO_STARTUP_MODE=rotate",
+	}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Config{OutputPath: "b.log", MaxSize: 1024, MaxBackups: 9,
+		LockWait: 2 * time.Minute, StartupMode: StartupRotateExisting}
+	if *cfg != exp {
+		t.Fatalf("mismatch, exp: %+v, got: %+v", exp, *cfg)
+	}
+
+	// Sizes are in bytes in developed mode, whatever the order of variables.
+	cfg = new(Config)
+	err = loadConfigEnv([]string{"LOGRO_MAX_SIZE_MB=4KB", "LOGRO_PER_WRITE_SIZE=16", "LOGRO_DEVELOPED=true"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxSize != 4096 || cfg.PerWriteSize != 16 || !cfg.Developed {
+		t.Fatalf("mismatch developed sizes: %+v", *cfg)
+	}
+
+	for _, kv := range []string{"LOGRO_MAX_BACKUPS=many", "LOGRO_FRAMED=yes", "LOGRO_PER_SYNC_SIZE=1KB"} {
+		if err = loadConfigEnv([]string{kv}, new(Config)); err == nil {
+			t.Fatalf("%s should be illegal", kv)
+		}
+	}
+
+	err = loadConfigEnv([]string{"LOGRO_MAX_SIZE=1GiB", "LOGRO_MAX_BACKUPS=9", "LOGRO_UNKNOWN=1"}, new(Config))
+	if err == nil || !strings.Contains(err.Error(), "LOGRO_MAX_SIZE, LOGRO_UNKNOWN") {
+		t.Fatal("should fail with unknown variables", err)
+	}
+}
+
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logro.toml")
+	err = ioutil.WriteFile(path, []byte(`output_path = "`+filepath.Join(dir, "a.log")+`"
+max_backups = 8
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("LOGRO_MAX_BACKUPS", "2")
+	defer os.Unsetenv("LOGRO_MAX_BACKUPS")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxBackups != 2 {
+		t.Fatal("mismatch")
+	}
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// ErrLocked is returned by New when the log file is held by another process
+// (or it's in the directory of a running Manager).
+var ErrLocked = errors.New("log file is locked by another process")
+
+const lockExt = ".lock"
+
+// lockRetryInterval is the interval of retrying flock when waiting for the lock.
+const lockRetryInterval = 10 * time.Millisecond
+
+// makeLockFP returns the lock file path of the log file.
+// e.g. a.log -> a.log.lock
+//
+// The lock file won't be treated as backup, because its ext is different.
+func makeLockFP(outputPath string) string {
+	return outputPath + lockExt
+}
+
+// lockFile acquires an advisory exclusive lock on outputPath's lock file.
+//
+// If wait is 0, returns ErrLocked immediately when another process holds the lock,
+// if wait < 0, waits until getting the lock,
+// otherwise waits for wait at most.
+func lockFile(outputPath string, wait time.Duration) (f *os.File, err error) {
+
+	fp := makeLockFP(outputPath)
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make dirs for lock file: %s", err.Error())
+	}
+
+	f, err = os.OpenFile(fp, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %s", err.Error())
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return f, nil
+		}
+		if err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %s", fp, err.Error())
+		}
+		if wait == 0 || (wait > 0 && time.Now().After(deadline)) {
+			f.Close()
+			return nil, fmt.Errorf("%w: %s", ErrLocked, fp)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// lockLogFile is lockFile for the log file of New (or UpdateConfig),
+// the log file mustn't be in the directory of a running Manager (or its subdirectories),
+// because Manager's Rotations don't hold the locks of their log files (saving file descriptors).
+//
+// The lock is taken before checking Manager's, and Manager.Get checks the lock of the log file,
+// so one of them fails if they're racing.
+func lockLogFile(outputPath string, wait time.Duration) (f *os.File, err error) {
+
+	f, err = lockFile(outputPath, wait)
+	if err != nil {
+		return
+	}
+
+	fp, err := filepath.Abs(outputPath)
+	if err != nil {
+		unlockFile(f)
+		return nil, fmt.Errorf("failed to get absolute path of log file: %s", err.Error())
+	}
+	for dir := filepath.Dir(fp); ; dir = filepath.Dir(dir) {
+		mfp := makeLockFP(filepath.Join(dir, managerLockName))
+		locked, err := isLocked(mfp)
+		if err != nil || locked {
+			unlockFile(f)
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %s is managed by %s", ErrLocked, outputPath, mfp)
+		}
+		if filepath.Dir(dir) == dir {
+			return f, nil
+		}
+	}
+}
+
+// isLocked returns true if the lock file fp is held by others.
+// It returns false if fp doesn't exist.
+func isLocked(fp string) (bool, error) {
+
+	f, err := os.Open(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open lock file: %s", err.Error())
+	}
+	defer f.Close() // Releasing the shared lock.
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock %s: %s", fp, err.Error())
+	}
+	return false, nil
+}
+
+// unlockFile releases the lock and closes the lock file.
+//
+// The lock file is kept on disk, removing it may make two processes
+// hold locks on different inodes.
+func unlockFile(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return f.Close()
+}
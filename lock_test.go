@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewLocked(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "logro-test.log")
+	r, err := New(&Config{OutputPath: fp, Developed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(&Config{OutputPath: fp, Developed: true})
+	if !errors.Is(err, ErrLocked) {
+		t.Fatal("should be locked", err)
+	}
+
+	_, err = New(&Config{OutputPath: fp, Developed: true, LockWait: 20 * time.Millisecond})
+	if !errors.Is(err, ErrLocked) {
+		t.Fatal("should be locked after waiting", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		r.Close()
+	}()
+
+	r2, err := New(&Config{OutputPath: fp, Developed: true, LockWait: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2.Close()
+
+	r3, err := New(&Config{OutputPath: fp, Developed: true})
+	if err != nil {
+		t.Fatal("lock should be released after closing", err)
+	}
+	r3.Close()
+}
+
+func TestLockFileIsNotBackup(t *testing.T) {
+	prefix, ext := getPrefixAndExt("a/b.log")
+	if parseTime(makeLockFP("a/b.log"), prefix, ext) != 0 {
+		t.Fatal("lock file should not be a backup")
+	}
+}
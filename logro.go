@@ -33,21 +33,68 @@ type Rotation struct {
 
 	backups *Backups
 
-	f   *os.File
-	buf *diodes.ManyToOne
+	stats Stats
+
+	frameHeader [maxFrameHeaderSize]byte
+
+	lock *os.File
+	f    *os.File
+	// fileSize is the size of f when it's opened.
+	fileSize int64
+	// reserved is the size preallocated for f.
+	reserved int64
+	buf      ringBuffer
+	// seq is the last sequence number in sequenced mode.
+	seq     uint64
+	reorder *reorderBuffer
+	// tee is the *MemSink set by Tee.
+	tee unsafe.Pointer
+
+	// Writing state, only accessed by writeLoop (or Manager's writer).
+	bufw    *bufIO
+	dirty   int
+	written int
+
+	// Syncing state, only accessed by syncLoop (or Manager's syncer).
+	syncOffset int64
+	syncN      int64
+	// dropOffset is the start of the range not dropped from page cache (CacheDropBehind).
+	dropOffset int64
+	// syncCfg is the Config seen by syncLoop,
+	// it's updated by flushJob after UpdateConfig.
+	syncCfg *Config
+	// fs is the page cache control of log files.
+	fs fileSystem
+
+	// Managed by Manager (without its own loops).
+	mgr      *Manager
+	name     string
+	worker   *managerWorker
+	syncer   int
+	lastUsed int64 // LRU clock of worker.
+	// notified is 1 if r is notified to worker since the last visit.
+	notified int32
+	// opened is true if the log file has been opened (by StartupMode), it's opened on the first write.
+	opened bool
 
 	syncJob    chan struct{}
 	flushJobs  chan flushJob
+	updates    chan configUpdate
+	done       chan struct{} // Closed after the writer finished.
 	ctx        context.Context
 	loopCtx    context.Context
 	loopCancel func()
 	loopWg     sync.WaitGroup
 }
 
+// ErrClosed is returned by Rotation.UpdateConfig after closing.
+var ErrClosed = errors.New("rotation closed")
+
 // New creates a Rotation.
+// It returns *ConfigError if cfg is invalid (see Config.Validate & Config.Lenient).
 func New(cfg *Config) (r *Rotation, err error) {
 
-	r, err = prepare(cfg)
+	r, err = prepare(cfg, false)
 	if err != nil {
 		return
 	}
@@ -57,74 +104,233 @@ func New(cfg *Config) (r *Rotation, err error) {
 	return
 }
 
-func prepare(cfg *Config) (r *Rotation, err error) {
+// prepare prepares Rotation by cfg.
+// If dirLocked, the caller (Manager) holds the lock of the directory,
+// and there is no lock for the log file,
+// the log file isn't opened until the first write.
+func prepare(cfg *Config, dirLocked bool) (r *Rotation, err error) {
 
-	if cfg.OutputPath == "" {
-		return nil, errors.New("empty log file path")
+	cfg, err = prepareConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	cfg.adjust()
+	var lock *os.File
+	if !dirLocked {
+		lock, err = lockLogFile(cfg.OutputPath, cfg.LockWait)
+		if err != nil {
+			return
+		}
+	}
+	defer func() {
+		if err != nil {
+			unlockFile(lock)
+		}
+	}()
 
-	r = &Rotation{cfg: cfg}
+	sc := *cfg
+	r = &Rotation{cfg: cfg, syncCfg: &sc, fs: osFS{}, lock: lock}
 	bs, err := listBackups(cfg.OutputPath, cfg.MaxBackups)
 	if err != nil {
-		return
+		return nil, err
 	}
 	r.backups = bs
 
-	err = r.open()
-	if err != nil {
-		return
+	if !dirLocked {
+		err = r.openExisting()
+		if err != nil {
+			return nil, err
+		}
+		r.written = int(r.fileSize)
+		// Data before fileSize has been written in previous runs (StartupAppend).
+		r.syncOffset, r.dropOffset = r.fileSize, r.fileSize
 	}
 
-	r.buf = diodes.NewManyToOne(cfg.BufItem, nil)
+	r.buf = newRingBuffer(cfg.BufItem, cfg.Shards, diodes.AlertFunc(func(missed int) {
+		atomic.AddInt64(&r.stats.Dropped, int64(missed))
+	}))
+	if cfg.Sequenced {
+		r.reorder = newReorderBuffer(cfg.ReorderWindow)
+	}
 	r.syncJob = make(chan struct{}, 1)
 	r.flushJobs = make(chan flushJob, 16)
+	r.updates = make(chan configUpdate, 1)
+	r.done = make(chan struct{})
 
 	return
 }
 
+// prepareConfig validates cfg (unless it's Lenient),
+// returns an adjusted copy of it (cfg is unchanged).
+func prepareConfig(cfg *Config) (*Config, error) {
+
+	if !cfg.Lenient {
+		err := cfg.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.OutputPath == "" {
+		return nil, errors.New("empty log file path")
+	}
+	if !cfg.StartupMode.isValid() {
+		return nil, fmt.Errorf("unknown startup mode: %s", cfg.StartupMode)
+	}
+	if !cfg.CachePolicy.isValid() {
+		return nil, fmt.Errorf("unknown cache policy: %s", cfg.CachePolicy)
+	}
+
+	c := *cfg
+	c.adjust()
+	return &c, nil
+}
+
+// openExisting opens the log file which may exist when logro starts,
+// dealing with it according to StartupMode.
+func (r *Rotation) openExisting() (err error) {
+
+	switch r.cfg.StartupMode {
+	case StartupAppend:
+		return r.openAppend()
+	case StartupRotateExisting:
+		fi, err := os.Stat(r.cfg.OutputPath)
+		if err == nil && fi.Size() > 0 {
+			err = r.moveToBackup()
+			if err != nil {
+				return err
+			}
+		}
+		return r.open()
+	default:
+		return r.open()
+	}
+}
+
 // open opens a new log file.
 // If log file existed, move it to backups.
 func (r *Rotation) open() (err error) {
 
-	fp := r.cfg.OutputPath
-
 	if r.f != nil { // File exist may happen in rotation process.
-		backupFP, t := makeBackupFP(fp, r.cfg.LocalTime, time.Now())
-		err = os.Rename(fp, backupFP)
+		err = r.moveToBackup()
 		if err != nil {
-			return fmt.Errorf("failed to rename log file, output: %s backup: %s", fp, backupFP)
+			return
 		}
+	}
+
+	// Truncate here to clean up file content if someone else creates
+	// the file between exist checking and create file.
+	// Can't use os.O_EXCL here, because it may break rotation process.
+	//
+	// Most of log shippers monitor file size, and APPEND only can avoid Read-Modify-Write.
+	return r.openFile(os.O_WRONLY | os.O_CREATE | os.O_TRUNC | os.O_APPEND)
+}
+
+// openAppend opens the log file for continuing writing,
+// the file will be created if it doesn't exist.
+//
+// The torn tail left by crash will be discarded before opening,
+// and a recovery marker will be written.
+func (r *Rotation) openAppend() (err error) {
 
-		heap.Push(r.backups, Backup{t, backupFP})
-		if r.backups.Len() > r.cfg.MaxBackups {
-			v := heap.Pop(r.backups)
-			os.Remove(v.(Backup).fp)
+	discarded, err := recoverFile(r.cfg.OutputPath, r.cfg.PerSyncSize, r.cfg.Framed)
+	if err != nil {
+		return
+	}
+
+	if discarded > 0 { // Written before opening, the log file may be opened for direct I/O.
+		atomic.StoreInt64(&r.stats.RecoveredBytes, discarded)
+		err = appendFile(r.cfg.OutputPath, makeRecoveryMarker(discarded, time.Now(), r.cfg.Framed))
+		if err != nil {
+			return fmt.Errorf("failed to write recovery marker: %s", err.Error())
 		}
 	}
 
-	// Create a new log file.
+	err = r.openFile(os.O_WRONLY | os.O_CREATE | os.O_APPEND)
+	if err != nil {
+		return
+	}
+
+	fi, err := r.f.Stat()
+	if err != nil {
+		r.f.Close()
+		return fmt.Errorf("failed to stat log file: %s", err.Error())
+	}
+	r.fileSize = fi.Size()
+	return
+}
+
+// appendFile appends p to the file at fp.
+func appendFile(fp string, p []byte) error {
+
+	f, err := os.OpenFile(fp, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(p)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// openFile opens the log file with flag,
+// in direct I/O mode, O_APPEND is replaced by O_DIRECT (writing at offsets).
+func (r *Rotation) openFile(flag int) (err error) {
+
+	fp := r.cfg.OutputPath
+	if r.cfg.DirectIO {
+		flag = flag&^os.O_APPEND | oDirect
+	}
+
 	dir := filepath.Dir(fp)
 	err = os.MkdirAll(dir, 0755) // ensure we have created the right dir.
 	if err != nil {
 		return fmt.Errorf("failed to make dirs for log file: %s", err.Error())
 	}
-	// Truncate here to clean up file content if someone else creates
-	// the file between exist checking and create file.
-	// Can't use os.O_EXCL here, because it may break rotation process.
-	//
-	// Most of log shippers monitor file size, and APPEND only can avoid Read-Modify-Write.
-	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
 	f, err := fnc.OpenFile(fp, flag, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create log file: %s", err.Error())
 	}
 
 	r.f = f
+	r.fileSize = 0
+	r.reserved = 0
+	if r.cfg.Preallocate && preallocate(f, r.cfg.MaxSize) == nil {
+		r.reserved = r.cfg.MaxSize
+	}
+	if r.mgr != nil {
+		atomic.AddInt64(&r.mgr.openFiles, 1)
+	}
 	return
 }
 
+// moveToBackup renames the log file to a backup,
+// and removes the oldest backup if there are too many.
+func (r *Rotation) moveToBackup() (err error) {
+
+	fp := r.cfg.OutputPath
+
+	backupFP, t := makeBackupFP(fp, r.cfg.LocalTime, time.Now())
+	err = os.Rename(fp, backupFP)
+	if err != nil {
+		return fmt.Errorf("failed to rename log file, output: %s backup: %s", fp, backupFP)
+	}
+
+	heap.Push(r.backups, Backup{t, backupFP})
+	r.trimBackups()
+	return
+}
+
+// trimBackups removes the oldest backups if there are more than MaxBackups.
+func (r *Rotation) trimBackups() {
+	for r.backups.Len() > r.cfg.MaxBackups {
+		v := heap.Pop(r.backups)
+		removeBackup(v.(Backup).fp)
+	}
+}
+
 func (r *Rotation) run() {
 	r.startLoop()
 	atomic.StoreInt64(&r.isRunning, 1)
@@ -137,14 +343,69 @@ func (r *Rotation) startLoop() {
 	go r.syncLoop()
 }
 
+// entry is the item in Rotation's buffer.
+type entry struct {
+	p []byte
+	// seq is the sequence number in sequenced mode, starts from 1.
+	seq uint64
+	// owned is the buffer holding p passed by WriteOwned,
+	// it will be freed after writing p.
+	owned OwnedBuffer
+}
+
 // Write writes data to buffer then notify file write.
+//
+// p is written in background, so it must not be modified after Write.
+// Use WriteOwned if p is from a pool.
 func (r *Rotation) Write(p []byte) (written int, err error) {
 
 	if r.isClosed() {
 		return
 	}
 
-	r.buf.Set(unsafe.Pointer(&p))
+	r.teeWrite(p)
+	r.buf.Set(unsafe.Pointer(r.makeEntry(p, nil)))
+	r.notify()
+
+	return len(p), nil
+}
+
+func (r *Rotation) makeEntry(p []byte, owned OwnedBuffer) *entry {
+	e := &entry{p: p, owned: owned}
+	if r.cfg.Sequenced {
+		e.seq = atomic.AddUint64(&r.seq, 1)
+	}
+	return e
+}
+
+// nextEntry returns the next entry in buffer (in sequence order in sequenced mode).
+func (r *Rotation) nextEntry() (*entry, bool) {
+	if r.reorder != nil {
+		return r.reorder.next(r.buf)
+	}
+	p, ok := r.buf.TryNext()
+	if !ok {
+		return nil, false
+	}
+	return (*entry)(p), true
+}
+
+// WriteOwned is like Write, but takes the ownership of b,
+// b will be freed after writing to the log file.
+// It's safe to use buffers from a pool (e.g. GetBuffer).
+//
+// If b is overwritten in buffer (see Stats.Dropped), it's left to GC.
+func (r *Rotation) WriteOwned(b OwnedBuffer) (written int, err error) {
+
+	if r.isClosed() {
+		b.Free()
+		return
+	}
+
+	p := b.Bytes()
+	r.teeWrite(p)
+	r.buf.Set(unsafe.Pointer(r.makeEntry(p, b)))
+	r.notify()
 
 	return len(p), nil
 }
@@ -157,6 +418,7 @@ func (r *Rotation) Sync() (err error) {
 	}
 
 	r.syncJob <- struct{}{}
+	r.notify()
 
 	return
 }
@@ -168,16 +430,30 @@ func (r *Rotation) Close() (err error) {
 		return
 	}
 
+	if r.mgr != nil {
+		return r.mgr.remove(r)
+	}
+
 	r.stopLoop()
+	close(r.done)
 
 	close(r.flushJobs)
+	for job := range r.flushJobs { // Left by syncLoop, the retired log files must be synced & closed.
+		r.flush(job)
+	}
 
 	r.buf = nil
 
 	if r.f != nil { // Just in case.
-		return r.f.Close()
+		if r.bufw != nil && r.bufw.wa != nil {
+			r.f.Truncate(r.bufw.size()) // Removing padding.
+		}
+		releasePrealloc(r.f, r.reserved)
+		err = r.f.Close()
 	}
 
+	unlockFile(r.lock)
+
 	return
 }
 
@@ -194,6 +470,17 @@ type flushJob struct {
 	f     *os.File
 	size  int64
 	isOld bool
+	// length is the length of f when it's retired (isOld),
+	// it may be larger than MaxSize (the last record) or smaller (switching OutputPath).
+	length int64
+	// next is the size of the new log file when f is retired (isOld),
+	// it isn't zero if the new one is opened for appending (switching OutputPath).
+	next int64
+	// isEvicted is true if f is closed by Manager for saving file descriptors,
+	// Rotation will reopen the log file for writing.
+	isEvicted bool
+	// cfg is the new Config (f is nil) after UpdateConfig.
+	cfg *Config
 }
 
 func (r *Rotation) writeLoop() {
@@ -203,56 +490,219 @@ func (r *Rotation) writeLoop() {
 	ctx, cancel := context.WithCancel(r.loopCtx)
 	defer cancel()
 
-	bufw := newBufIO(r.f, int(r.cfg.PerWriteSize))
-	dirty := 0
-	written := 0
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
 		case <-r.syncJob:
-			for i := 0; i < r.cfg.BufItem; i++ { // There is a limit, avoiding blocking.
-				p, ok := r.buf.TryNext()
-				if !ok {
-					break
-				}
-				_, fw, _ := bufw.write(*(*[]byte)(p))
-				dirty += fw
-				written += fw
-			}
-			fw, _ := bufw.flush()
-			dirty += fw
-			written += fw
+			r.writeBuffered()
+
+		case u := <-r.updates:
+			u.errc <- r.applyConfig(u.cfg)
 
 		default:
-			p, ok := r.buf.TryNext()
+			e, ok := r.nextEntry()
 			if !ok {
 				time.Sleep(2 * time.Millisecond)
 				continue
 			}
-			_, fw, _ := bufw.write(*(*[]byte)(p))
-			dirty += fw
-			written += fw
+			r.write(e)
+		}
+	}
+}
 
-			if int64(dirty) >= r.cfg.PerSyncSize {
-				r.flushJobs <- flushJob{r.f, int64(dirty), false}
-				dirty = 0
-			}
+// getBufIO returns the write buffer, creates it if it's nil.
+func (r *Rotation) getBufIO() *bufIO {
+	if r.bufw == nil {
+		if r.cfg.DirectIO {
+			r.bufw = r.newDirectBufIO()
+		} else {
+			r.bufw = newBufIO(fileWriter{r}, int(r.cfg.PerWriteSize))
+		}
+	}
+	return r.bufw
+}
+
+// newDirectBufIO creates the write buffer in direct I/O mode,
+// continuing writing from the end of data (written) of the log file.
+func (r *Rotation) newDirectBufIO() *bufIO {
+
+	size := int64(r.written)
+	var tail []byte
+	if n := size & (directAlign - 1); n > 0 {
+		tail = make([]byte, n)
+		f, err := os.Open(r.cfg.OutputPath)
+		if err == nil {
+			_, err = f.ReadAt(tail, size-n)
+			f.Close()
+		}
+		if err != nil {
+			// Writing from the block boundary, the last partial block will be lost,
+			// it's better than refusing all writes.
+			tail = nil
+		}
+	}
+	return newDirectBufIO(fileWriter{r}, int(r.cfg.PerWriteSize), size, tail)
+}
+
+// flushFile flushes the write buffer to the current log file before leaving it (e.g. rotation).
+// In direct I/O mode, the padding of the last block is truncated.
+func (r *Rotation) flushFile() {
 
-			if int64(written) >= r.cfg.MaxSize {
-				written = 0 // Avoiding keeping renew file if we can't create new file.
-				oldF := r.f
-				err := r.open()
-				if err == nil {
-					r.flushJobs <- flushJob{oldF, 0, true}
-					bufw.reset(r.f)
-				}
+	if r.bufw == nil {
+		return
+	}
+	fw, _ := r.bufw.flush()
+	r.account(fw)
+	if r.bufw.wa != nil {
+		r.f.Truncate(r.bufw.size())
+	}
+}
+
+// dropDirectBuf drops the write buffer after leaving the log file in direct I/O mode,
+// it holds the last block of the old file.
+// It's kept if leaving fails, the old file will be written from where it ends.
+func (r *Rotation) dropDirectBuf() {
+	if r.bufw != nil && r.bufw.wa != nil {
+		r.bufw = nil
+	}
+}
+
+// releaseReserved releases the space preallocated for the log file before leaving it.
+//
+// It's done by writer, which is the only one writing the log file:
+// the syncer may close the file after it has been reopened for appending (by Manager or UpdateConfig),
+// truncating to the size seen before reopening would cut off new data.
+func (r *Rotation) releaseReserved() {
+	releasePrealloc(r.f, r.reserved)
+	r.reserved = 0
+}
+
+// account accounts fw bytes written to the log file.
+func (r *Rotation) account(fw int) {
+	r.dirty += fw
+	r.written += fw
+	atomic.AddInt64(&r.stats.Written, int64(fw))
+}
+
+// writeBuffered writes records in buffer and flushes them to the log file.
+func (r *Rotation) writeBuffered() {
+
+	bufw := r.getBufIO()
+	for i := 0; i < r.cfg.BufItem*r.cfg.Shards; i++ { // There is a limit, avoiding blocking.
+		e, ok := r.nextEntry()
+		if !ok {
+			break
+		}
+		r.account(r.writeEntry(bufw, e))
+	}
+	if r.reorder != nil { // Not waiting for missing entries.
+		for {
+			e, ok := r.reorder.flush()
+			if !ok {
+				break
 			}
+			r.account(r.writeEntry(bufw, e))
+		}
+	}
+	fw, _ := bufw.flush()
+	r.account(fw)
+}
+
+// write writes e, then syncs or rotates the log file if it's necessary.
+func (r *Rotation) write(e *entry) {
+
+	bufw := r.getBufIO()
+	r.account(r.writeEntry(bufw, e))
+
+	if int64(r.dirty) >= r.cfg.PerSyncSize {
+		r.sendFlush(flushJob{f: r.f, size: int64(r.dirty)})
+		r.dirty = 0
+	}
+
+	if int64(r.written) >= r.cfg.MaxSize {
+		// Flush the rest of records to the old file,
+		// making each log file ends with a complete record.
+		r.flushFile()
+		r.releaseReserved()
+		length := int64(r.written)
+		oldF := r.f
+		err := r.open()
+		if err != nil {
+			// Keeping writing the old file (retrying rotation at the next write),
+			// written is kept for continuing from the end of it in direct I/O mode.
+			return
 		}
+		r.written = 0
+		r.dropDirectBuf()
+		r.sendFlush(flushJob{f: oldF, isOld: true, length: length})
 	}
 }
 
+// fileWriter writes to the current log file of Rotation,
+// the log file will be reopened if it's closed by Manager.
+type fileWriter struct {
+	r *Rotation
+}
+
+func (w fileWriter) Write(p []byte) (int, error) {
+	if w.r.f == nil {
+		err := w.r.reopen()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return w.r.f.Write(p)
+}
+
+// WriteAt is used in direct I/O mode (not supported by Manager, so the file is never closed).
+func (w fileWriter) WriteAt(p []byte, off int64) (int, error) {
+	return w.r.f.WriteAt(p, off)
+}
+
+// writeEntry writes e into bufw, frees the owned buffer after that.
+func (r *Rotation) writeEntry(bufw *bufIO, e *entry) (fw int) {
+
+	fw = r.writeRecord(bufw, e.p, e.seq)
+	if e.owned != nil {
+		e.owned.Free() // p has been copied to bufw or written to file.
+	}
+	return
+}
+
+// writeRecord writes p into bufw (framing p with seq (if it's not zero) in framed mode),
+// returns the number of bytes written to file.
+func (r *Rotation) writeRecord(bufw *bufIO, p []byte, seq uint64) (fw int) {
+
+	if !r.cfg.Framed {
+		_, fw, _ = bufw.write(p)
+		return
+	}
+
+	if int64(len(p)) > MaxFramePayload {
+		atomic.AddInt64(&r.stats.Oversized, 1)
+		return
+	}
+	m := frameMeta{flags: frameFlagTime, ts: time.Now().UnixNano(), seq: seq}
+	if seq != 0 {
+		m.flags |= frameFlagSeq
+	}
+	n := putFrameHeader(r.frameHeader[:], m, p)
+	_, hw, _ := bufw.write(r.frameHeader[:n])
+	_, fw, _ = bufw.write(p)
+	return hw + fw
+}
+
+// sendFlush sends job to syncLoop (or Manager's syncer).
+func (r *Rotation) sendFlush(job flushJob) {
+	if r.mgr != nil {
+		r.mgr.syncers[r.syncer] <- syncTask{r: r, job: job}
+		return
+	}
+	r.flushJobs <- job
+}
+
 func (r *Rotation) syncLoop() {
 
 	defer r.loopWg.Done()
@@ -260,31 +710,89 @@ func (r *Rotation) syncLoop() {
 	ctx, cancel := context.WithCancel(r.loopCtx)
 	defer cancel()
 
-	n := int64(0)
-	offset := int64(0)
-
 	for {
 		select {
 		case job := <-r.flushJobs:
-			if !job.isOld {
-				n += job.size
-				if n >= r.cfg.PerSyncSize {
-					fnc.FlushHint(job.f, offset, n)
-					offset += n
-					n = 0
-				}
-			} else {
-				fnc.FlushHint(job.f, 0, r.cfg.MaxSize)
-				fnc.DropCache(job.f, 0, r.cfg.MaxSize)
-				job.f.Close()
-
-				// Will have a new file in the next round.
-				offset = 0
-				n = 0
-			}
+			r.flush(job)
 
 		case <-ctx.Done():
 			return
 		}
 	}
 }
+
+// flush flushes dirty data in page cache by job.
+func (r *Rotation) flush(job flushJob) {
+
+	switch {
+	case job.cfg != nil:
+		r.syncCfg = job.cfg
+
+	case job.isOld:
+		r.retire(job)
+
+		// Will have a new file in the next round.
+		r.syncOffset = job.next
+		r.syncN = 0
+		r.dropOffset = job.next
+
+	case job.isEvicted:
+		r.syncN += job.size
+		if r.syncN > 0 {
+			r.flushHint(job.f)
+		}
+		r.flushError(job.f.Close())
+
+	default:
+		r.syncN += job.size
+		if r.syncN >= r.syncCfg.PerSyncSize {
+			r.flushHint(job.f)
+		}
+	}
+}
+
+// retire syncs the whole retired log file [0, job.length) to disk, then closes it.
+// CachePolicy only controls the flushing hint & dropping.
+//
+// The dirty pages are synced before dropping, otherwise they can't be dropped.
+func (r *Rotation) retire(job flushJob) {
+
+	policy := r.syncCfg.CachePolicy
+	if policy != CacheKernel {
+		r.flushError(r.fs.FlushHint(job.f, 0, job.length))
+	}
+	r.flushError(r.fs.Fdatasync(job.f))
+	if policy == CacheDropRotated || policy == CacheDropBehind {
+		r.flushError(r.fs.DropCache(job.f, 0, job.length))
+	}
+	r.flushError(job.f.Close())
+}
+
+// flushError counts err (if it's not nil) in Stats.FlushErrors.
+func (r *Rotation) flushError(err error) {
+	if err != nil {
+		atomic.AddInt64(&r.stats.FlushErrors, 1)
+	}
+}
+
+// flushHint flushes the dirty range [syncOffset, syncOffset+syncN) of f (by CachePolicy).
+//
+// In CacheDropBehind, the ranges flushed in previous rounds are dropped from page cache,
+// the writeback of them has been started, they're likely clean now
+// (the newly flushed range is still under writeback, it can't be dropped).
+func (r *Rotation) flushHint(f *os.File) {
+
+	policy := r.syncCfg.CachePolicy
+	if policy != CacheKernel {
+		start := time.Now()
+		r.flushError(r.fs.FlushHint(f, r.syncOffset, r.syncN))
+		atomic.AddInt64(&r.stats.Syncs, 1)
+		atomic.AddInt64((*int64)(&r.stats.SyncTime), int64(time.Since(start)))
+	}
+	if policy == CacheDropBehind && r.syncOffset > r.dropOffset {
+		r.flushError(r.fs.DropCache(f, r.dropOffset, r.syncOffset-r.dropOffset))
+		r.dropOffset = r.syncOffset
+	}
+	r.syncOffset += r.syncN
+	r.syncN = 0
+}
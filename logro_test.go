@@ -205,10 +205,12 @@ func TestRotation_WriteMaxSizeConcurrent(t *testing.T) {
 					time.Sleep(100 * time.Microsecond) // Avoiding too fast write.
 					n, err := r.Write([]byte{v})
 					if err != nil {
-						tr.Fatal(err, i)
+						tr.Error(err, i)
+						return
 					}
 					if n != 1 {
-						tr.Fatal("written mismatch")
+						tr.Error("written mismatch")
+						return
 					}
 				}
 
@@ -260,3 +262,104 @@ func isMatchFileContent(p []byte, output string) bool {
 
 	return bytes.Equal(p, act)
 }
+
+func TestStartupMode(t *testing.T) {
+
+	old := []byte("old\n")
+	for _, mode := range []StartupMode{"", StartupTruncate, StartupAppend, StartupRotateExisting} {
+		dir, err := ioutil.TempDir(os.TempDir(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp := filepath.Join(dir, "logro-test.log")
+		err = ioutil.WriteFile(fp, old, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := New(&Config{OutputPath: fp, MaxSize: 1024, PerWriteSize: 4, PerSyncSize: 16,
+			Developed: true, StartupMode: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Write([]byte("new\n"))
+		r.Sync()
+		time.Sleep(4 * time.Millisecond)
+		r.Close()
+
+		exp := []byte("new\n")
+		if mode == StartupAppend {
+			exp = append(old, exp...)
+		}
+		act, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(exp, act) {
+			t.Fatalf("mode: %s content mismatch, exp: %q, act: %q", mode, exp, act)
+		}
+
+		bs, err := listBackups(fp, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode == StartupRotateExisting {
+			if bs.Len() != 1 || !isMatchFileContent(old, bs.bs[0].fp) {
+				t.Fatal("existed log file should be moved to backups")
+			}
+		} else if bs.Len() != 0 {
+			t.Fatalf("mode: %s should not have backups", mode)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStartupAppendMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "logro-test.log")
+	existed := []byte("line-00\nline-01\nline-02\nline-03\n") // Complete records, nothing to recover.
+	err = ioutil.WriteFile(fp, existed, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(&Config{OutputPath: fp, MaxSize: int64(len(existed)), PerWriteSize: 1, PerSyncSize: 16,
+		Developed: true, StartupMode: StartupAppend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Stats().RecoveredBytes != 0 {
+		t.Fatal("existed records shouldn't be recovered", r.Stats().RecoveredBytes)
+	}
+
+	r.Write([]byte("line-04\n"))
+	waitBackups(t, fp, 1)
+
+	bs, err := listBackups(fp, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs.Len() != 1 {
+		t.Fatal("should rotate because the existed log file is full")
+	}
+	p, err := ioutil.ReadFile(bs.bs[0].fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != string(existed)+"line-04\n" {
+		t.Fatal("the existed records should be kept in the backup", string(p))
+	}
+}
+
+func TestStartupModeUnknown(t *testing.T) {
+	_, err := New(&Config{OutputPath: filepath.Join(os.TempDir(), "logro-test.log"), StartupMode: "x"})
+	if err == nil {
+		t.Fatal("should fail with unknown startup mode")
+	}
+}
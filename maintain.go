@@ -0,0 +1,172 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/templexxx/fnc"
+)
+
+// LogFile is a logro-managed log file.
+type LogFile struct {
+	Path string
+	Size int64
+	// Time is the backup timestamp, it's zero for the active log file.
+	Time       time.Time
+	Active     bool
+	Compressed bool
+}
+
+// ListFiles lists backups (from the oldest to the newest) and
+// the active log file (if it exists) of outputPath.
+func ListFiles(outputPath string) (files []LogFile, err error) {
+
+	bs, err := scanBackups(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bs.sorted() {
+		fi, err := os.Stat(b.fp)
+		if err != nil {
+			continue // Removed by rotation.
+		}
+		files = append(files, LogFile{
+			Path:       b.fp,
+			Size:       fi.Size(),
+			Time:       time.Unix(b.ts, 0),
+			Compressed: isCompressed(b.fp),
+		})
+	}
+
+	fi, err := os.Stat(outputPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return files, nil
+		}
+		return nil, err
+	}
+	files = append(files, LogFile{
+		Path:   outputPath,
+		Size:   fi.Size(),
+		Active: true,
+	})
+	return files, nil
+}
+
+// PruneOptions is the retention policy of Prune.
+// Zero value means no limit.
+type PruneOptions struct {
+	// MaxBackups is the maximum number of backups to retain.
+	MaxBackups int
+	// MaxAge is the maximum age of backups (by backup timestamp) to retain.
+	MaxAge time.Duration
+	// MaxTotalSize is the maximum total size of backups & the active log file.
+	// The active log file won't be removed.
+	MaxTotalSize int64
+}
+
+// Prune removes the oldest backups of outputPath which are out of retention policy,
+// returns the removed files.
+func Prune(outputPath string, opts PruneOptions) (removed []string, err error) {
+
+	files, err := ListFiles(outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []LogFile
+	total := int64(0)
+	for _, f := range files {
+		total += f.Size
+		if !f.Active {
+			backups = append(backups, f)
+		}
+	}
+
+	now := time.Now()
+	for i, b := range backups {
+		remain := len(backups) - i
+		if (opts.MaxBackups <= 0 || remain <= opts.MaxBackups) &&
+			(opts.MaxAge <= 0 || now.Sub(b.Time) <= opts.MaxAge) &&
+			(opts.MaxTotalSize <= 0 || total <= opts.MaxTotalSize) {
+			break
+		}
+		err = os.Remove(b.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove backup: %s", err.Error())
+		}
+		removed = append(removed, b.Path)
+		total -= b.Size
+	}
+	return removed, nil
+}
+
+// Compress compresses the backup fp to fp.gz (gzip), then removes fp.
+// It returns the compressed file path.
+//
+// Compressed backups are still managed by logro and could be read by Iterator.
+func Compress(fp string) (dst string, err error) {
+
+	if isCompressed(fp) {
+		return "", fmt.Errorf("%s is already compressed", fp)
+	}
+
+	src, err := os.Open(fp)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst = fp + compressExt
+	tmp := dst + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	w := gzip.NewWriter(f)
+	_, err = io.Copy(w, src)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	err = f.Sync()
+	if err != nil {
+		return "", err
+	}
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	// Rename after sync, making sure that there is no broken compressed backup.
+	err = os.Rename(tmp, dst)
+	if err != nil {
+		return "", err
+	}
+	err = fnc.SyncDir(filepath.Dir(dst))
+	if err != nil {
+		return "", err
+	}
+	return dst, os.Remove(fp)
+}
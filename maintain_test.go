@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListFiles(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	_, err = makeTestLogFiles(output, 3, 2, false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ListFiles(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatal("mismatch files count")
+	}
+	if !files[0].Compressed || files[1].Compressed {
+		t.Fatal("the oldest backup should be compressed")
+	}
+	for i, f := range files {
+		if f.Active != (i == 3) {
+			t.Fatal("only the last one is active")
+		}
+		if i > 0 && i < 3 && !files[i-1].Time.Before(f.Time) {
+			t.Fatal("backups should be sorted")
+		}
+		if f.Size == 0 {
+			t.Fatal("mismatch size")
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	_, err = makeTestLogFiles(output, 5, 2, false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ListFiles(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Prune(output, PruneOptions{MaxBackups: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != files[0].Path {
+		t.Fatal("the oldest backup should be removed by MaxBackups")
+	}
+
+	total := int64(0)
+	for _, f := range files[2:] {
+		total += f.Size
+	}
+	removed, err = Prune(output, PruneOptions{MaxTotalSize: total})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != files[1].Path {
+		t.Fatal("the oldest backup should be removed by MaxTotalSize")
+	}
+
+	removed, err = Prune(output, PruneOptions{MaxTotalSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Fatal("all backups should be removed")
+	}
+	files, err = ListFiles(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !files[0].Active {
+		t.Fatal("the active log file should be kept")
+	}
+}
+
+func TestCompress(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	exp, err := makeTestLogFiles(output, 3, 2, false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ListFiles(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Compress(files[0].Path)
+	if err == nil {
+		t.Fatal("should not compress a compressed backup")
+	}
+	dst, err := Compress(files[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dst != files[1].Path+compressExt {
+		t.Fatal("mismatch compressed path")
+	}
+
+	files, err = ListFiles(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 || !files[1].Compressed {
+		t.Fatal("compressed backup should be listed")
+	}
+
+	it, err := Open(output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var act [][]byte
+	for it.Next() {
+		act = append(act, append([]byte(nil), it.Record().Data...))
+	}
+	if !isMatchRecords(exp, act) {
+		t.Fatal("records mismatch")
+	}
+
+	removeBackup(files[2].Path)
+	if _, err = os.Stat(files[2].Path); !os.IsNotExist(err) {
+		t.Fatal("backup should be removed")
+	}
+	removeBackup(files[1].Path[:len(files[1].Path)-len(compressExt)])
+	if _, err = os.Stat(files[1].Path); !os.IsNotExist(err) {
+		t.Fatal("compressed backup should be removed")
+	}
+}
+
+func TestPruneMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	now := time.Now()
+	var fps []string
+	for i := 3; i > 0; i-- {
+		fp, _ := makeBackupFP(output, false, now.Add(-time.Duration(i)*time.Hour))
+		err = ioutil.WriteFile(fp, []byte("a\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fps = append(fps, fp)
+	}
+
+	removed, err := Prune(output, PruneOptions{MaxAge: 90 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || removed[0] != fps[0] || removed[1] != fps[1] {
+		t.Fatal("backups older than MaxAge should be removed", removed)
+	}
+}
@@ -0,0 +1,545 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ManagerConfig is the config of Manager.
+type ManagerConfig struct {
+	// Dir is the directory of log files,
+	// the log file of name is Dir/name.
+	Dir string `json:"dir" toml:"dir"`
+	// Template is the Config of Rotations, OutputPath & LockWait are ignored.
+	//
+	// StartupAppend is recommended, because a Rotation will be created again
+	// by Get after closing it.
+	Template Config `json:"template" toml:"template"`
+	// Writers is the number of goroutines writing log files.
+	// Default: 4.
+	Writers int `json:"writers" toml:"writers"`
+	// Syncers is the number of goroutines flushing dirty pages.
+	// Default: 4.
+	Syncers int `json:"syncers" toml:"syncers"`
+	// MaxOpenFiles is the budget of open log files,
+	// the least recently written files will be closed when it's exceeded,
+	// and reopened when there are new records.
+	// Default: 1024.
+	//
+	// Each writer has MaxOpenFiles/Writers of the budget.
+	MaxOpenFiles int `json:"max_open_files" toml:"max_open_files"`
+}
+
+const (
+	defaultManagerWriters = 4
+	defaultManagerSyncers = 4
+	defaultMaxOpenFiles   = 1024
+)
+
+// managerBatch is the max number of records written for a Rotation
+// before moving to the next one, avoiding starving others.
+const managerBatch = 256
+
+// managerLockName is the name of lock file in Manager's directory.
+const managerLockName = ".logro-manager"
+
+// ErrManagerClosed is returned by Manager.Get after closing.
+var ErrManagerClosed = errors.New("manager closed")
+
+// Manager manages many Rotations (e.g. per-tenant log files) in a directory,
+// they share a fixed number of writer & syncer goroutines
+// instead of having their own loops.
+//
+// Each Rotation is bound to a writer & a syncer by its name.
+// A Rotation notifies its writer when it has new records (or Sync, UpdateConfig),
+// writers only visit notified Rotations, idle ones cost nothing.
+// Log files are opened on the first write.
+type Manager struct {
+	cfg ManagerConfig
+
+	lock *os.File
+
+	mu     sync.Mutex
+	closed bool
+	rs     map[string]*Rotation
+
+	workers []*managerWorker
+	syncers []chan syncTask
+
+	// openFiles is the number of open log files.
+	openFiles int64
+
+	ctx       context.Context
+	cancel    func()
+	workerWg  sync.WaitGroup
+	syncersWg sync.WaitGroup
+}
+
+// syncTask is flushJob of a managed Rotation.
+type syncTask struct {
+	r   *Rotation
+	job flushJob
+}
+
+// NewManager creates a Manager.
+// Manager holds an advisory lock on the directory while running.
+func NewManager(cfg *ManagerConfig) (m *Manager, err error) {
+
+	if cfg.Dir == "" {
+		return nil, errors.New("empty log directory")
+	}
+
+	m = &Manager{cfg: *cfg, rs: make(map[string]*Rotation)}
+	m.adjust()
+
+	err = os.MkdirAll(m.cfg.Dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make log directory: %s", err.Error())
+	}
+	m.lock, err = lockFile(filepath.Join(m.cfg.Dir, managerLockName), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	budget := m.cfg.MaxOpenFiles / m.cfg.Writers
+	if budget < 1 {
+		budget = 1
+	}
+	for i := 0; i < m.cfg.Writers; i++ {
+		w := &managerWorker{
+			m:       m,
+			budget:  budget,
+			adds:    make(chan *Rotation),
+			removes: make(chan removeReq),
+			wake:    make(chan struct{}, 1),
+		}
+		m.workers = append(m.workers, w)
+		m.workerWg.Add(1)
+		go w.run()
+	}
+	for i := 0; i < m.cfg.Syncers; i++ {
+		ch := make(chan syncTask, 256)
+		m.syncers = append(m.syncers, ch)
+		m.syncersWg.Add(1)
+		go m.syncLoop(ch)
+	}
+	return m, nil
+}
+
+func (m *Manager) adjust() {
+	if m.cfg.Writers <= 0 {
+		m.cfg.Writers = defaultManagerWriters
+	}
+	if m.cfg.Syncers <= 0 {
+		m.cfg.Syncers = defaultManagerSyncers
+	}
+	if m.cfg.MaxOpenFiles <= 0 {
+		m.cfg.MaxOpenFiles = defaultMaxOpenFiles
+	}
+}
+
+// Get returns the Rotation of name, creates it if it doesn't exist.
+// name is the path of log file relative to Dir (e.g. "tenant-a.log").
+//
+// The log file is opened (by StartupMode) on the first write,
+// errors of opening it are returned by the file writes.
+//
+// Rotation.Close removes it from Manager, the next Get creates a new one.
+// It returns ErrLocked if the log file is held by New (managed Rotations hold the lock of Dir only).
+func (m *Manager) Get(name string) (r *Rotation, err error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	if r, ok := m.rs[name]; ok {
+		return r, nil
+	}
+
+	fp, err := m.outputPath(name)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make dirs for log file: %s", err.Error())
+	}
+	cfg := m.cfg.Template
+	if cfg.DirectIO {
+		return nil, errors.New("DirectIO isn't supported by Manager")
+	}
+	cfg.OutputPath = fp
+	locked, err := isLocked(makeLockFP(fp)) // Held by New in another process.
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, fmt.Errorf("%w: %s", ErrLocked, makeLockFP(fp))
+	}
+	r, err = prepare(&cfg, true)
+	if err != nil {
+		return nil, err
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := int(h.Sum32())
+	r.mgr = m
+	r.name = name
+	r.worker = m.workers[sum%len(m.workers)]
+	r.syncer = sum % len(m.syncers)
+	atomic.StoreInt64(&r.isRunning, 1)
+
+	r.worker.adds <- r
+	m.rs[name] = r
+	return r, nil
+}
+
+func (m *Manager) outputPath(name string) (string, error) {
+	if name == "" || filepath.IsAbs(name) || filepath.Clean(name) != name ||
+		name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal log file name: %s", name)
+	}
+	return filepath.Join(m.cfg.Dir, name), nil
+}
+
+// remove removes r from Manager after writing all buffered records.
+func (m *Manager) remove(r *Rotation) error {
+
+	req := removeReq{r: r, done: make(chan struct{})}
+	select {
+	case r.worker.removes <- req:
+		<-req.done
+	case <-m.ctx.Done(): // Manager is closing, it will be finished by worker.
+		return nil
+	}
+
+	m.mu.Lock()
+	if m.rs[r.name] == r {
+		delete(m.rs, r.name)
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// Stats returns the sum of statistics of all Rotations.
+func (m *Manager) Stats() (st Stats) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.rs {
+		st.add(r.Stats())
+	}
+	return
+}
+
+// Close closes all Rotations after writing their buffered records,
+// then releases all resources.
+func (m *Manager) Close() error {
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	for _, r := range m.rs {
+		atomic.StoreInt64(&r.isRunning, 0)
+	}
+	m.mu.Unlock()
+
+	m.cancel()
+	m.workerWg.Wait()
+	for _, ch := range m.syncers {
+		close(ch)
+	}
+	m.syncersWg.Wait()
+
+	return unlockFile(m.lock)
+}
+
+func (m *Manager) syncLoop(tasks chan syncTask) {
+
+	defer m.syncersWg.Done()
+
+	for t := range tasks {
+		t.r.flush(t.job)
+		if t.job.isOld || t.job.isEvicted {
+			atomic.AddInt64(&m.openFiles, -1)
+		}
+	}
+}
+
+type removeReq struct {
+	r    *Rotation
+	done chan struct{}
+}
+
+// managerWorker writes records of its Rotations,
+// and keeps the number of open files in its budget.
+type managerWorker struct {
+	m       *Manager
+	rs      []*Rotation
+	budget  int
+	open    int
+	clock   int64
+	adds    chan *Rotation
+	removes chan removeReq
+
+	// Rotations notified by writes, Sync & UpdateConfig.
+	mu    sync.Mutex
+	queue []*Rotation
+	wake  chan struct{}
+
+	// Only accessed by worker.
+	// next is the Rotations to be visited in the next round (having more records than managerBatch).
+	next []*Rotation
+	// waiting is the Rotations holding entries for reordering (sequenced mode),
+	// they're visited every managerWaitInterval until the entries are written.
+	waiting   []*Rotation
+	waitSince time.Time
+}
+
+// managerWaitInterval is the interval of visiting Rotations holding entries for reordering.
+const managerWaitInterval = 2 * time.Millisecond
+
+// notify notifies the writer of r that r has new work.
+// It's called after the work is put (a record, syncJob, or update).
+func (r *Rotation) notify() {
+	if r.mgr == nil {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&r.notified, 0, 1) { // Not notified since the last visit.
+		w := r.worker
+		w.mu.Lock()
+		w.queue = append(w.queue, r)
+		w.mu.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *managerWorker) run() {
+
+	defer w.m.workerWg.Done()
+
+	for {
+		select {
+		case <-w.m.ctx.Done():
+			for _, r := range w.rs {
+				w.finish(r)
+			}
+			w.rs = nil
+			return
+		case r := <-w.adds:
+			w.rs = append(w.rs, r)
+			continue
+		case req := <-w.removes:
+			w.remove(req)
+			continue
+		default:
+		}
+
+		rs := w.takeWork()
+		if len(rs) == 0 {
+			w.idle()
+			continue
+		}
+		for _, r := range rs {
+			w.visit(r)
+		}
+	}
+}
+
+// takeWork returns the Rotations to be visited in this round.
+func (w *managerWorker) takeWork() (rs []*Rotation) {
+
+	rs, w.next = w.next, nil
+	w.mu.Lock()
+	rs = append(rs, w.queue...)
+	w.queue = w.queue[:0]
+	w.mu.Unlock()
+
+	if len(w.waiting) > 0 && time.Since(w.waitSince) >= managerWaitInterval {
+		rs = append(rs, w.waiting...)
+		w.waiting = nil
+	}
+	return
+}
+
+// idle waits for new work.
+func (w *managerWorker) idle() {
+
+	var timeout <-chan time.Time
+	if len(w.waiting) > 0 {
+		t := time.NewTimer(managerWaitInterval - time.Since(w.waitSince))
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-w.m.ctx.Done():
+	case r := <-w.adds:
+		w.rs = append(w.rs, r)
+	case req := <-w.removes:
+		w.remove(req)
+	case <-w.wake:
+	case <-timeout:
+	}
+}
+
+// remove finishes the Rotation of req.
+func (w *managerWorker) remove(req removeReq) {
+	for i, r := range w.rs {
+		if r == req.r {
+			w.finish(r)
+			w.rs = append(w.rs[:i], w.rs[i+1:]...)
+			break
+		}
+	}
+	close(req.done)
+}
+
+// visit handles the work of r.
+func (w *managerWorker) visit(r *Rotation) {
+
+	select {
+	case <-r.done: // Finished.
+		return
+	default:
+	}
+
+	atomic.StoreInt32(&r.notified, 0) // Work put after this will notify again.
+
+	select {
+	case <-r.syncJob:
+		r.writeBuffered()
+	default:
+	}
+	select {
+	case u := <-r.updates:
+		u.errc <- r.applyConfig(u.cfg)
+	default:
+	}
+
+	for i := 0; i < managerBatch; i++ {
+		e, ok := r.nextEntry()
+		if !ok {
+			if r.reorder != nil && len(r.reorder.h) > 0 && !w.isWaiting(r) {
+				if len(w.waiting) == 0 {
+					w.waitSince = time.Now()
+				}
+				w.waiting = append(w.waiting, r)
+			}
+			return
+		}
+		if !r.opened { // Knowing the size of the existed log file before writing.
+			r.reopen()
+		}
+		w.clock++
+		r.lastUsed = w.clock
+		r.write(e)
+	}
+	w.next = append(w.next, r) // There may be more.
+}
+
+func (w *managerWorker) isWaiting(r *Rotation) bool {
+	for _, o := range w.waiting {
+		if o == r {
+			return true
+		}
+	}
+	return false
+}
+
+// finish writes all buffered records of r, then closes its log file.
+func (w *managerWorker) finish(r *Rotation) {
+	r.writeBuffered()
+	w.evict(r)
+	close(r.done)
+}
+
+// opened is called after opening the log file of r,
+// it closes the least recently written files if the budget is exceeded.
+func (w *managerWorker) opened(r *Rotation) {
+
+	w.clock++
+	r.lastUsed = w.clock
+	w.open++
+	for w.open > w.budget {
+		var lru *Rotation
+		for _, o := range w.rs {
+			if o != r && o.f != nil && (lru == nil || o.lastUsed < lru.lastUsed) {
+				lru = o
+			}
+		}
+		if lru == nil {
+			return
+		}
+		w.evict(lru)
+	}
+}
+
+// evict flushes the buffered data of r, and closes its log file (by syncer).
+// The write buffer is released too, it will be recreated after reopening.
+func (w *managerWorker) evict(r *Rotation) {
+
+	if r.f == nil {
+		return
+	}
+	if r.bufw != nil {
+		fw, _ := r.bufw.flush()
+		r.account(fw)
+		r.bufw = nil
+	}
+	r.releaseReserved()
+	r.sendFlush(flushJob{f: r.f, size: int64(r.dirty), isEvicted: true})
+	r.dirty = 0
+	r.f = nil
+	w.open--
+}
+
+// reopen opens the log file of Rotation managed by Manager,
+// it's opened by StartupMode at the first time,
+// and reopened for appending after closing by Manager.
+func (r *Rotation) reopen() (err error) {
+
+	if !r.opened {
+		err = r.openExisting()
+		if err != nil {
+			return err
+		}
+		r.opened = true
+		r.written = int(r.fileSize)
+		// No flushJob has been sent for the log file yet.
+		r.syncOffset, r.dropOffset = r.fileSize, r.fileSize
+	} else {
+		err = r.openFile(os.O_WRONLY | os.O_CREATE | os.O_APPEND)
+		if err != nil {
+			return err
+		}
+	}
+	r.worker.opened(r)
+	return nil
+}
@@ -0,0 +1,366 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func makeTestManager(t *testing.T, dir string) *Manager {
+	m, err := NewManager(&ManagerConfig{
+		Dir: dir,
+		Template: Config{
+			MaxSize:      4096,
+			MaxBackups:   100,
+			StartupMode:  StartupAppend,
+			BufItem:      1024,
+			PerWriteSize: 64,
+			PerSyncSize:  256,
+			Developed:    true,
+		},
+		Writers:      2,
+		Syncers:      2,
+		MaxOpenFiles: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// checkManagedRecords checks records of name across backups & the active log file.
+func checkManagedRecords(t *testing.T, dir, name string, cnt int) {
+	it, err := Open(filepath.Join(dir, name), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	i := 0
+	for it.Next() {
+		exp := fmt.Sprintf("%s-%d", name, i)
+		if string(it.Record().Data) != exp {
+			t.Fatal("mismatch record", string(it.Record().Data), exp)
+		}
+		i++
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if i != cnt {
+		t.Fatal("mismatch records", name, i, cnt)
+	}
+}
+
+func TestManager(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := makeTestManager(t, dir)
+
+	names, rounds, perRound := 16, 20, 10
+	for i := 0; i < rounds; i++ {
+		for j := 0; j < names; j++ {
+			name := fmt.Sprintf("tenant-%d.log", j)
+			r, err := m.Get(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k := 0; k < perRound; k++ {
+				r.Write([]byte(fmt.Sprintf("%s-%d\n", name, i*perRound+k)))
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt64(&m.openFiles); n > int64(m.cfg.MaxOpenFiles) {
+		t.Fatal("too many open files", n)
+	}
+
+	r0, _ := m.Get("tenant-0.log")
+	r1, _ := m.Get("tenant-0.log")
+	if r0 != r1 {
+		t.Fatal("should get the same Rotation")
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&m.openFiles); n != 0 {
+		t.Fatal("all files should be closed", n)
+	}
+	if st := m.Stats(); st.Dropped != 0 {
+		t.Fatal("should not drop records", st)
+	}
+	for j := 0; j < names; j++ {
+		checkManagedRecords(t, dir, fmt.Sprintf("tenant-%d.log", j), rounds*perRound)
+	}
+
+	_, err = m.Get("tenant-0.log")
+	if err != ErrManagerClosed {
+		t.Fatal("should be closed", err)
+	}
+}
+
+func TestManager_CloseRotation(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := makeTestManager(t, dir)
+	defer m.Close()
+
+	name := "a/b.log"
+	r, err := m.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte(name + "-0\n"))
+	err = r.Close() // Buffered records should be written.
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r2, err := m.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r2 == r {
+		t.Fatal("should create a new Rotation after closing")
+	}
+	r2.Write([]byte(name + "-1\n"))
+	r2.Close()
+
+	checkManagedRecords(t, dir, name, 2)
+}
+
+func TestManager_IllegalName(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := makeTestManager(t, dir)
+	defer m.Close()
+
+	for _, name := range []string{"", "/a.log", "../a.log", "..", "a/../b.log", "a//b.log"} {
+		_, err := m.Get(name)
+		if err == nil {
+			t.Fatal("should fail", name)
+		}
+	}
+}
+
+func TestManager_Locked(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := makeTestManager(t, dir)
+	defer m.Close()
+
+	_, err = NewManager(&ManagerConfig{Dir: dir})
+	if !errors.Is(err, ErrLocked) {
+		t.Fatal("should be locked", err)
+	}
+}
+
+func TestManager_LazyOpen(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := makeTestManager(t, dir)
+	defer m.Close()
+
+	fp := filepath.Join(dir, "a.log")
+	err = ioutil.WriteFile(fp, []byte("a.log-0\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 8; i++ {
+		_, err = m.Get(fmt.Sprintf("idle-%d.log", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := m.Get("a.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&m.openFiles); n != 0 {
+		t.Fatal("log files should be opened on the first write", n)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "idle-0.log")); !os.IsNotExist(err) {
+		t.Fatal("log file shouldn't be created before writing", err)
+	}
+
+	r.Write([]byte("a.log-1\n"))
+	waitWritten(t, r, 8)
+	if n := atomic.LoadInt64(&m.openFiles); n != 1 {
+		t.Fatal("mismatch open files", n)
+	}
+	r.Close()
+	checkManagedRecords(t, dir, "a.log", 2) // Opened by StartupAppend.
+}
+
+// Writers only visit notified Rotations.
+func TestManager_Notify(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := makeTestManager(t, dir)
+	defer m.Close()
+
+	names := 1000
+	rs := make([]*Rotation, names)
+	for i := range rs {
+		rs[i], err = m.Get(fmt.Sprintf("tenant-%d.log", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for round := 0; round < 3; round++ {
+		time.Sleep(10 * time.Millisecond) // Idle.
+		for i := 0; i < names; i += 100 {
+			rs[i].Write([]byte(fmt.Sprintf("tenant-%d.log-%d\n", i, round)))
+		}
+	}
+	for i := 0; i < names; i += 100 {
+		waitWritten(t, rs[i], int64(len(fmt.Sprintf("tenant-%d.log-0\n", i))*3))
+	}
+
+	visited := func() bool {
+		for _, w := range m.workers {
+			w.mu.Lock()
+			n := len(w.queue)
+			w.mu.Unlock()
+			if n != 0 {
+				return false
+			}
+		}
+		for _, r := range rs {
+			if atomic.LoadInt32(&r.notified) != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; !visited(); i++ {
+		if i == 100 {
+			t.Fatal("all notified Rotations should be visited")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := range rs {
+		if i%100 != 0 {
+			if _, err = os.Stat(filepath.Join(dir, fmt.Sprintf("tenant-%d.log", i))); !os.IsNotExist(err) {
+				t.Fatal("idle Rotation shouldn't open its log file", i)
+			}
+		}
+	}
+}
+
+func TestManager_Sequenced(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := NewManager(&ManagerConfig{
+		Dir:      dir,
+		Template: Config{BufItem: 1024, Sequenced: true, ReorderWindow: 64},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	r, err := m.Get("a.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.Write([]byte("0123456789\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	waitWritten(t, r, 4*100*11)
+}
+
+// Manager and New don't write the same log file.
+func TestManager_LockedFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := New(&Config{OutputPath: filepath.Join(dir, "a.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := makeTestManager(t, dir)
+	_, err = m.Get("a.log")
+	if !errors.Is(err, ErrLocked) {
+		t.Fatal("should be locked by New", err)
+	}
+	_, err = m.Get("b.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	_, err = m.Get("a.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.log", "c.log", "sub/d.log"} {
+		_, err = New(&Config{OutputPath: filepath.Join(dir, name)})
+		if !errors.Is(err, ErrLocked) {
+			t.Fatal("should be locked by Manager", name, err)
+		}
+	}
+
+	m.Close()
+	r, err = New(&Config{OutputPath: filepath.Join(dir, "c.log")})
+	if err != nil {
+		t.Fatal("lock should be released after closing Manager", err)
+	}
+	r.Close()
+}
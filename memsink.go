@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// MemSink is a bounded in-memory log sink keeping the last records written to it,
+// it has the write methods of Rotation (Write, WriteOwned, Sync & Close).
+//
+// It's useful in testing (asserting on output without log files),
+// and for dumping recent logs on panic (by Rotation.Tee, even if file writes lagged):
+//
+//	m := NewMemSink(1<<20, 0)
+//	r.Tee(m)
+//	defer func() {
+//		if v := recover(); v != nil {
+//			m.WriteTo(os.Stderr)
+//			panic(v)
+//		}
+//	}()
+//
+// Records are copied, it's safe for concurrent use.
+type MemSink struct {
+	mu         sync.Mutex
+	maxBytes   int
+	maxRecords int
+
+	recs   [][]byte // From the oldest to the newest.
+	size   int      // Total bytes of recs.
+	closed bool
+}
+
+// defaultMemSinkSize is the maxBytes of MemSink if there is no limit.
+const defaultMemSinkSize = int(mb)
+
+// NewMemSink creates a MemSink keeping at most maxBytes & maxRecords of the last records,
+// the oldest records are evicted when any limit is exceeded.
+// A limit <= 0 means no limit, maxBytes is 1MB if both are <= 0.
+//
+// A record larger than maxBytes is truncated to its last maxBytes.
+func NewMemSink(maxBytes, maxRecords int) *MemSink {
+
+	if maxBytes <= 0 && maxRecords <= 0 {
+		maxBytes = defaultMemSinkSize
+	}
+	return &MemSink{maxBytes: maxBytes, maxRecords: maxRecords}
+}
+
+// Write copies p into MemSink, it never fails.
+// p is ignored after Close.
+func (m *MemSink) Write(p []byte) (written int, err error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.add(p)
+	return len(p), nil
+}
+
+// WriteOwned is like Write, b is freed after copying.
+func (m *MemSink) WriteOwned(b OwnedBuffer) (written int, err error) {
+	written, err = m.Write(b.Bytes())
+	b.Free()
+	return
+}
+
+// Sync does nothing, records are kept in memory.
+func (m *MemSink) Sync() (err error) {
+	return
+}
+
+// Close stops MemSink accepting records, the kept records could still be read.
+func (m *MemSink) Close() (err error) {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	return
+}
+
+// add appends a copy of p, evicting the oldest records out of limits.
+func (m *MemSink) add(p []byte) {
+
+	if m.maxBytes > 0 && len(p) > m.maxBytes {
+		p = p[len(p)-m.maxBytes:]
+	}
+	b := make([]byte, len(p))
+	copy(b, p)
+	m.recs = append(m.recs, b)
+	m.size += len(b)
+
+	for (m.maxBytes > 0 && m.size > m.maxBytes) ||
+		(m.maxRecords > 0 && len(m.recs) > m.maxRecords) {
+		m.size -= len(m.recs[0])
+		m.recs[0] = nil
+		m.recs = m.recs[1:]
+	}
+}
+
+// Records returns the kept records from the oldest to the newest,
+// they must not be modified.
+func (m *MemSink) Records() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]byte(nil), m.recs...)
+}
+
+// Bytes returns a copy of the kept records in order.
+func (m *MemSink) Bytes() []byte {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := make([]byte, 0, m.size)
+	for _, b := range m.recs {
+		p = append(p, b...)
+	}
+	return p
+}
+
+// Len returns the number of the kept records.
+func (m *MemSink) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.recs)
+}
+
+// WriteTo writes the kept records in order to w (e.g. dumping them to stderr on panic).
+// It implements io.WriterTo.
+func (m *MemSink) WriteTo(w io.Writer) (n int64, err error) {
+
+	for _, b := range m.Records() { // Not holding lock when writing to w.
+		var nn int
+		nn, err = w.Write(b)
+		n += int64(nn)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Reset removes all the kept records.
+func (m *MemSink) Reset() {
+	m.mu.Lock()
+	m.recs, m.size = nil, 0
+	m.mu.Unlock()
+}
+
+// Tee copies records written by Write & WriteOwned to m (before buffering them),
+// so m has them even if they're dropped or not written to the log file yet.
+// Tee(nil) stops copying.
+//
+// It costs a copy & a lock per write, use it for debugging or crash dumps.
+func (r *Rotation) Tee(m *MemSink) {
+	atomic.StorePointer(&r.tee, unsafe.Pointer(m))
+}
+
+// teeWrite copies p to the MemSink set by Tee.
+func (r *Rotation) teeWrite(p []byte) {
+	if m := (*MemSink)(atomic.LoadPointer(&r.tee)); m != nil {
+		m.Write(p)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestMemSink_Limits(t *testing.T) {
+
+	m := NewMemSink(10, 3)
+	for _, s := range []string{"a", "bb", "ccc", "dddd"} {
+		m.Write([]byte(s))
+	}
+	if string(m.Bytes()) != "bbcccdddd" || m.Len() != 3 { // Limited by records.
+		t.Fatal("mismatch", string(m.Bytes()), m.Len())
+	}
+	m.Write([]byte("eeeee"))
+	if string(m.Bytes()) != "ddddeeeee" || m.Len() != 2 { // Limited by bytes.
+		t.Fatal("mismatch", string(m.Bytes()), m.Len())
+	}
+	m.Write([]byte("0123456789abc"))
+	if string(m.Bytes()) != "3456789abc" || m.Len() != 1 { // Truncated.
+		t.Fatal("mismatch", string(m.Bytes()), m.Len())
+	}
+
+	m.Reset()
+	if len(m.Bytes()) != 0 || m.Len() != 0 {
+		t.Fatal("should be empty after reset")
+	}
+
+	m = NewMemSink(0, 0)
+	m.Write(make([]byte, defaultMemSinkSize+1))
+	if len(m.Bytes()) != defaultMemSinkSize {
+		t.Fatal("mismatch default size", len(m.Bytes()))
+	}
+}
+
+func TestMemSink_Write(t *testing.T) {
+
+	m := NewMemSink(0, 4)
+
+	p := []byte("abc")
+	m.Write(p)
+	p[0] = 'x' // Copied.
+
+	b := GetBuffer()
+	b.WriteString("def")
+	n, err := m.WriteOwned(b)
+	if err != nil || n != 3 {
+		t.Fatal("mismatch", n, err)
+	}
+
+	recs := m.Records()
+	if len(recs) != 2 || string(recs[0]) != "abc" || string(recs[1]) != "def" {
+		t.Fatal("mismatch", recs)
+	}
+
+	var buf bytes.Buffer
+	n64, err := m.WriteTo(&buf)
+	if err != nil || n64 != 6 || buf.String() != "abcdef" {
+		t.Fatal("mismatch", n64, err, buf.String())
+	}
+
+	if m.Sync() != nil || m.Close() != nil {
+		t.Fatal("should be ok")
+	}
+	n, _ = m.Write([]byte("ghi"))
+	if n != 0 || string(m.Bytes()) != "abcdef" {
+		t.Fatal("should ignore writes after close")
+	}
+}
+
+func TestMemSink_Concurrent(t *testing.T) {
+
+	m := NewMemSink(0, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Write([]byte(fmt.Sprintf("%d-%d\n", i, j)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if m.Len() != 100 {
+		t.Fatal("mismatch", m.Len())
+	}
+}
+
+func TestRotation_Tee(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := New(&Config{OutputPath: filepath.Join(dir, "a.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	m := NewMemSink(0, 2)
+	r.Tee(m)
+	r.Write([]byte("a\n"))
+	b := GetBuffer()
+	b.WriteString("b\n")
+	r.WriteOwned(b)
+	r.Write([]byte("c\n"))
+
+	// Copied before buffering, no waiting for writeLoop.
+	if string(m.Bytes()) != "b\nc\n" {
+		t.Fatal("mismatch", string(m.Bytes()))
+	}
+
+	r.Tee(nil)
+	r.Write([]byte("d\n"))
+	if string(m.Bytes()) != "b\nc\n" {
+		t.Fatal("should stop copying", string(m.Bytes()))
+	}
+
+	waitWritten(t, r, 8)
+	p, err := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != "a\nb\nc\nd\n" {
+		t.Fatal("mismatch", string(p))
+	}
+}
@@ -8,11 +8,14 @@
 package logro
 
 import (
+	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // This bench is only for non-blocking model,
@@ -78,3 +81,101 @@ func BenchmarkRotation_WriteParallel(b *testing.B) {
 		}
 	})
 }
+
+// Shards reduce the contention on the ring buffer with many writers.
+func BenchmarkRotation_WriteParallelShards(b *testing.B) {
+	for _, shards := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("shards-%d", shards), func(b *testing.B) {
+			dir, err := ioutil.TempDir(os.TempDir(), "")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			cfg := new(Config)
+			cfg.OutputPath = filepath.Join(dir, "logro-perf-write-test.log")
+			cfg.Shards = shards
+
+			r, err := New(cfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer r.Close()
+
+			p := make([]byte, 256)
+			rand.Read(p)
+
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					r.Write(p)
+				}
+			})
+			b.StopTimer()
+			st := r.Stats()
+			b.ReportMetric(float64(st.Dropped)/float64(b.N), "dropped/op")
+		})
+	}
+}
+
+// Preallocate reduces extent allocation & metadata updates in syncing,
+// compare the sync latency & throughput of writing to disk.
+//
+// Run it on the target disk (TMPDIR), e.g.
+// TMPDIR=/data go test -run none -bench WritePreallocate -benchtime 200000x
+func BenchmarkRotation_WritePreallocate(b *testing.B) {
+	for _, prealloc := range []bool{false, true} {
+		b.Run(fmt.Sprintf("preallocate-%t", prealloc), func(b *testing.B) {
+			dir, err := ioutil.TempDir(os.TempDir(), "")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			r, err := New(&Config{
+				OutputPath:   filepath.Join(dir, "logro-perf-write-test.log"),
+				MaxSize:      64,
+				MaxBackups:   2,
+				BufItem:      8192,
+				PerSyncSize:  1,
+				PerWriteSize: 64,
+				Preallocate:  prealloc,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer r.Close()
+
+			p := make([]byte, 4096)
+			rand.Read(p)
+
+			// written waits until n bytes are written to the log file (or records dropped).
+			written := func(n int64) {
+				for atomic.LoadInt64(&r.stats.Written) < n && atomic.LoadInt64(&r.stats.Dropped) == 0 {
+					time.Sleep(100 * time.Microsecond)
+				}
+			}
+
+			start := time.Now()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Write(p)
+				if i%1024 == 0 && i >= 4096 { // Not dropping records.
+					written(int64((i - 4096) * len(p)))
+				}
+			}
+			r.Sync()
+			written(int64(b.N * len(p)))
+			b.StopTimer()
+			elapsed := time.Since(start)
+
+			time.Sleep(20 * time.Millisecond) // Waiting for syncer.
+			st := r.Stats()
+			if st.Syncs > 0 {
+				b.ReportMetric(float64(st.SyncTime)/float64(st.Syncs), "ns/sync")
+			}
+			b.ReportMetric(float64(st.Written)/elapsed.Seconds()/float64(mb), "MB/s")
+		})
+	}
+}
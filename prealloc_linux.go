@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE of fallocate(2).
+const fallocKeepSize = 0x1
+
+// preallocate reserves [0, size) of f without changing the file size,
+// so appending (O_APPEND) still starts from the end of data.
+func preallocate(f *os.File, size int64) error {
+	return syscall.Fallocate(int(f.Fd()), fallocKeepSize, 0, size)
+}
+
+// releasePrealloc releases the space reserved beyond the end of f.
+//
+// Truncating to the file size frees blocks beyond EOF
+// (punching hole doesn't work beyond EOF on some file systems, e.g. ext4).
+func releasePrealloc(f *os.File, reserved int64) error {
+
+	if reserved <= 0 {
+		return nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() >= reserved {
+		return nil
+	}
+	return f.Truncate(fi.Size())
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// allocated returns the allocated size of file fp.
+func allocated(t *testing.T, fp string) int64 {
+	fi, err := os.Stat(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Sys().(*syscall.Stat_t).Blocks * 512
+}
+
+func TestRotation_Preallocate(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "a.log")
+	f, err := os.Create(filepath.Join(dir, "probe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = preallocate(f, 4096)
+	f.Close()
+	if err != nil {
+		t.Skip("fallocate is not supported:", err)
+	}
+
+	const maxSize = 1 << 20
+	r, err := New(&Config{
+		OutputPath:        fp,
+		MaxSizeBytes:      maxSize,
+		PerWriteSizeBytes: 4096,
+		PerSyncSizeBytes:  8192,
+		Preallocate:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isMatchFileSize(0, fp) {
+		t.Fatal("file size should be unchanged")
+	}
+	if allocated(t, fp) < maxSize {
+		t.Fatal("should be preallocated")
+	}
+
+	// Rotate.
+	p := bytes.Repeat([]byte{'x'}, maxSize)
+	r.Write(p)
+	waitBackups(t, fp, 1)
+	for i := 0; i < 200 && allocated(t, fp) < maxSize; i++ { // Renamed before creating the new one.
+		time.Sleep(10 * time.Millisecond)
+	}
+	if allocated(t, fp) < maxSize {
+		t.Fatal("new log file should be preallocated")
+	}
+
+	r.Write([]byte("a\n"))
+	waitWritten(t, r, maxSize+2)
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := allocated(t, fp); n >= maxSize {
+		t.Fatal("reserved space should be released after closing", n)
+	}
+	if !isMatchFileContent([]byte("a\n"), fp) {
+		t.Fatal("content mismatch")
+	}
+}
+
+// slowFS makes syncer slow.
+type slowFS struct {
+	osFS
+	delay time.Duration
+}
+
+func (fs slowFS) FlushHint(f *os.File, offset, size int64) error {
+	time.Sleep(fs.delay)
+	return nil
+}
+
+// The reserved space of evicted log file is released by writer,
+// syncer may close it after it's reopened & appended.
+func TestManager_PreallocateEvict(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const maxSize = 1 << 20
+	m, err := NewManager(&ManagerConfig{
+		Dir:          dir,
+		Template:     Config{MaxSizeBytes: maxSize, StartupMode: StartupAppend, BufItem: 1024, Preallocate: true},
+		Writers:      1,
+		Syncers:      1,
+		MaxOpenFiles: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ra, err := m.Get("a.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra.fs = slowFS{delay: 200 * time.Millisecond}
+	ra.Write([]byte("a.log-0\n"))
+	waitWritten(t, ra, 8)
+	fp := filepath.Join(dir, "a.log")
+	if allocated(t, fp) < maxSize {
+		t.Skip("fallocate is not supported")
+	}
+
+	rb, err := m.Get("b.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb.Write([]byte("b.log-0\n"))
+	waitWritten(t, rb, 8) // a.log is evicted, syncer is flushing it slowly.
+	if n := allocated(t, fp); n >= maxSize {
+		t.Fatal("reserved space should be released before eviction", n)
+	}
+
+	ra.Write([]byte("a.log-1\n")) // Reopened & appended before syncer closing the evicted one.
+	waitWritten(t, ra, 16)
+	err = m.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkManagedRecords(t, dir, "a.log", 2)
+	checkManagedRecords(t, dir, "b.log", 1)
+}
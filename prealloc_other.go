@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import "os"
+
+// preallocate is a no-op, fallocate(2) is only available on Linux.
+func preallocate(f *os.File, size int64) error {
+	return nil
+}
+
+func releasePrealloc(f *os.File, reserved int64) error {
+	return nil
+}
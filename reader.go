@@ -0,0 +1,319 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+)
+
+// ReadOptions is the options of reading logro-managed log files.
+type ReadOptions struct {
+	// Framed is true if log files are written in framed mode (Config.Framed),
+	// otherwise records are delimited by newline.
+	Framed bool
+	// DirectIO is true if log files are written in direct I/O mode (Config.DirectIO),
+	// the zero padding of the last block of the active log file is skipped.
+	// It must be false for other log files, or records ending with zero bytes may be skipped.
+	DirectIO bool
+}
+
+// Record is a log record read from logro-managed log files.
+type Record struct {
+	// Data is the record without newline (or frame header in framed mode).
+	// It may be overwritten by the next read.
+	Data []byte
+	// File is the path of the log file which the record is in.
+	File string
+	// Offset is the offset of the record in File.
+	// For compressed backups, it's the offset in decompressed content.
+	Offset int64
+	// Time is the time when logro wrote the record in framed mode,
+	// it's zero if there is no time in the frame or not in framed mode.
+	Time time.Time
+	// Seq is the sequence number assigned by Write in sequenced mode (Config.Sequenced),
+	// it's zero if there is no sequence number in the frame or not in framed mode.
+	Seq uint64
+}
+
+// maxRecordSize is the max size of a line or a frame could be read.
+const maxRecordSize = maxFrameHeaderSize + int(MaxFramePayload)
+
+// recordSplitter splits records and tracks their offsets.
+type recordSplitter struct {
+	framed bool
+	frames frameSplitter
+
+	off    int64 // Offset of the next unread byte.
+	recOff int64 // Offset of the last record.
+}
+
+// split is a bufio.SplitFunc returns the whole line (without newline) or
+// the whole frame as token.
+func (s *recordSplitter) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+
+	if s.framed {
+		advance, token, err = s.frames.split(data, atEOF)
+		if token != nil {
+			s.recOff = s.off + int64(advance-len(token))
+		}
+	} else {
+		advance, token, err = scanLines(data, atEOF)
+		if token != nil {
+			s.recOff = s.off
+		}
+	}
+	s.off += int64(advance)
+	return
+}
+
+// record makes Record by token.
+func (s *recordSplitter) record(token []byte, fp string) Record {
+	rec := Record{File: fp, Offset: s.recOff}
+	if !s.framed {
+		rec.Data = token
+		return rec
+	}
+	m, p := parseFrame(token)
+	rec.Data = p
+	if m.flags&frameFlagTime != 0 {
+		rec.Time = time.Unix(0, m.ts)
+	}
+	rec.Seq = m.seq
+	return rec
+}
+
+// scanLines is a bufio.SplitFunc like bufio.ScanLines,
+// but keeps '\r' in the line.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Iterator iterates records in logro-managed log files in order,
+// from the oldest backup to the active log file.
+//
+// Compressed backups are decompressed transparently.
+// The active log file is read until its EOF when it's opened,
+// use Follow for reading new records.
+type Iterator struct {
+	opts     ReadOptions
+	allFiles []iterFile
+	files    []iterFile
+
+	seeking bool
+	from    time.Time
+	to      time.Time
+	started bool // Found the first record in [from, to].
+
+	f        *os.File
+	sc       *bufio.Scanner
+	splitter *recordSplitter
+	fp       string
+	rec      Record
+	skipped  int64
+	err      error
+}
+
+// Open opens logro-managed log files of outputPath for reading.
+func Open(outputPath string, opts *ReadOptions) (it *Iterator, err error) {
+
+	bs, err := scanBackups(outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	it = new(Iterator)
+	if opts != nil {
+		it.opts = *opts
+	}
+	for _, b := range bs.sorted() {
+		it.allFiles = append(it.allFiles, iterFile{b.fp, b.ts})
+	}
+	it.allFiles = append(it.allFiles, iterFile{outputPath, math.MaxInt64})
+	it.files = it.allFiles
+	return it, nil
+}
+
+// iterFile is the file will be read by Iterator.
+type iterFile struct {
+	fp string
+	// ts is the backup timestamp (the time of rotation),
+	// all records in the file are written before it.
+	ts int64
+}
+
+// Next advances the Iterator to the next record,
+// which will then be available through the Record method.
+// It returns false when there are no more records or an error happened.
+func (it *Iterator) Next() bool {
+
+	if it.err != nil {
+		return false
+	}
+
+	for {
+		if it.sc == nil {
+			if len(it.files) == 0 {
+				return false
+			}
+			fp := it.files[0].fp
+			it.files = it.files[1:]
+			err := it.open(fp)
+			if err != nil {
+				if os.IsNotExist(err) { // Removed by rotation.
+					continue
+				}
+				it.err = err
+				return false
+			}
+		}
+
+		if it.sc.Scan() {
+			it.rec = it.splitter.record(it.sc.Bytes(), it.fp)
+			if !it.seeking {
+				return true
+			}
+			ok, done := it.inRange(it.rec)
+			if done {
+				it.closeFile()
+				it.files = nil
+				return false
+			}
+			if ok {
+				return true
+			}
+			continue
+		}
+		err := it.sc.Err()
+		it.closeFile()
+		if err != nil {
+			it.err = fmt.Errorf("failed to read %s: %s", it.fp, err.Error())
+			return false
+		}
+	}
+}
+
+// openFile opens fp and makes scanner starting from offset.
+func (it *Iterator) openFile(fp string, offset int64) (err error) {
+
+	f, err := os.Open(fp)
+	if err != nil {
+		return
+	}
+
+	var rd io.Reader = f
+	if isCompressed(fp) {
+		rd, err = gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to decompress %s: %s", fp, err.Error())
+		}
+		if offset > 0 {
+			_, err = io.CopyN(ioutil.Discard, rd, offset)
+		}
+	} else {
+		if offset > 0 {
+			_, err = f.Seek(offset, io.SeekStart)
+		}
+		if err == nil && it.opts.DirectIO && fp == it.allFiles[len(it.allFiles)-1].fp { // Active log file.
+			var size int64
+			size, err = writtenSize(f, true)
+			rd = io.LimitReader(f, size-offset)
+		}
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to seek %s: %s", fp, err.Error())
+	}
+
+	it.f = f
+	it.fp = fp
+	it.splitter = &recordSplitter{framed: it.opts.Framed, off: offset}
+	it.sc = bufio.NewScanner(rd)
+	it.sc.Buffer(make([]byte, 64*kb), maxRecordSize)
+	it.sc.Split(it.splitter.split)
+	return nil
+}
+
+// writtenSize returns the size of data written to the active log file f.
+//
+// In direct I/O mode, the last block is padded with zeros and rewritten in place by the following writes,
+// so the trailing zeros in the last block of a file with aligned size are treated as not written yet.
+// (Records ending with zero bytes at an aligned size are delayed the same way,
+// until more records are written or the file is rotated.)
+func writtenSize(f *os.File, direct bool) (size int64, err error) {
+
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	size = fi.Size()
+	if !direct || size == 0 || size%directAlign != 0 {
+		return
+	}
+
+	block := make([]byte, directAlign)
+	_, err = f.ReadAt(block, size-directAlign)
+	if err != nil && err != io.EOF {
+		return
+	}
+	n := len(block)
+	for n > 0 && block[n-1] == 0 {
+		n--
+	}
+	return size - directAlign + int64(n), nil
+}
+
+func (it *Iterator) closeFile() {
+	if it.f != nil {
+		it.skipped += it.splitter.frames.skipped
+		it.f.Close()
+	}
+	it.f, it.sc, it.splitter = nil, nil, nil
+}
+
+// Record returns the current record.
+func (it *Iterator) Record() Record {
+	return it.rec
+}
+
+// Err returns the first error that was encountered by the Iterator.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Skipped returns the number of corrupt bytes skipped in framed mode.
+func (it *Iterator) Skipped() int64 {
+	if it.splitter != nil {
+		return it.skipped + it.splitter.frames.skipped
+	}
+	return it.skipped
+}
+
+// Close closes the Iterator.
+func (it *Iterator) Close() error {
+	it.closeFile()
+	it.files = nil
+	return nil
+}
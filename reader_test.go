@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeTestLogFiles makes n backups (the oldest one is compressed) and the active log file,
+// each file has m records.
+func makeTestLogFiles(output string, n, m int, framed bool, start time.Time) (records [][]byte, err error) {
+
+	for i := 0; i <= n; i++ {
+		var content []byte
+		for j := 0; j < m; j++ {
+			p := []byte(fmt.Sprintf("file-%d record-%d", i, j))
+			records = append(records, p)
+			if framed {
+				content = appendFrame(content, frameMeta{}, p)
+			} else {
+				content = append(append(content, p...), '\n')
+			}
+		}
+
+		fp := output
+		if i < n {
+			fp, _ = makeBackupFP(output, false, start.Add(time.Duration(i)*time.Second))
+		}
+		if i == 0 && n > 0 {
+			fp += compressExt
+			content, err = gzipBytes(content)
+			if err != nil {
+				return
+			}
+		}
+		err = ioutil.WriteFile(fp, content, 0644)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func gzipBytes(p []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	_, err := w.Write(p)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	return buf.Bytes(), err
+}
+
+func testIterator(t *testing.T, framed bool) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	exp, err := makeTestLogFiles(output, 3, 5, framed, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := Open(output, &ReadOptions{Framed: framed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	var act [][]byte
+	files := make(map[string]bool)
+	for it.Next() {
+		rec := it.Record()
+		act = append(act, append([]byte(nil), rec.Data...))
+		files[rec.File] = true
+
+		recSize := int64(len(rec.Data) + 1)
+		if framed {
+			recSize = int64(len(rec.Data) + frameHeaderSize)
+		}
+		if rec.Offset != int64(len(act)-1)%5*recSize {
+			t.Fatal("mismatch offset", rec.Offset)
+		}
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if !isMatchRecords(exp, act) {
+		t.Fatal("records mismatch")
+	}
+	if len(files) != 4 {
+		t.Fatal("should read all files")
+	}
+}
+
+func TestIterator(t *testing.T) {
+	testIterator(t, false)
+}
+
+func TestIteratorFramed(t *testing.T) {
+	testIterator(t, true)
+}
+
+func TestIteratorRotation(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	r, err := New(&Config{OutputPath: output, MaxSize: 64, MaxBackups: 32, PerWriteSize: 16, PerSyncSize: 32,
+		Developed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var exp [][]byte
+	for i := 0; i < 32; i++ {
+		p := []byte(fmt.Sprintf("record-%02d", i))
+		exp = append(exp, p)
+		r.Write(append(p, '\n'))
+		time.Sleep(2 * time.Millisecond) // Making backups have different timestamps.
+	}
+	r.Sync()
+	time.Sleep(10 * time.Millisecond)
+
+	it, err := Open(output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	var act [][]byte
+	for it.Next() {
+		act = append(act, append([]byte(nil), it.Record().Data...))
+	}
+	if !isMatchRecords(exp, act) {
+		t.Fatalf("records mismatch %q", act)
+	}
+}
+
+// Trailing zeros of an aligned log file are data if it's not written in direct I/O mode.
+func TestIteratorZeroTail(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	r, err := New(&Config{OutputPath: output, Framed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	p := make([]byte, directAlign-frameHeaderSize-extSize(frameFlagTime)) // Ending with zeros.
+	copy(p, "binary")
+	r.Write(p)
+	waitWritten(t, r, directAlign)
+
+	it, err := Open(output, &ReadOptions{Framed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	if !it.Next() || !bytes.Equal(it.Record().Data, p) {
+		t.Fatal("should read the record", it.Err())
+	}
+	if it.Next() || it.Skipped() != 0 {
+		t.Fatal("mismatch", it.Err(), it.Skipped())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fl, err := Follow(ctx, output, &FollowOptions{ReadOptions: ReadOptions{Framed: true}, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+	if act := followN(t, fl, 1); !bytes.Equal(act[0], p) {
+		t.Fatal("follower should read the record")
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// recoverMinWindow is the min size of log file tail which will be scanned in recovery.
+const recoverMinWindow = 64 * kb
+
+// recoverFile truncates the torn trailing record of the log file back to
+// the last complete record boundary (newline or valid frame).
+// It returns the number of discarded bytes.
+//
+// After a power loss, the data after the last sync may be partial or zero-filled,
+// so only the tail (at least PerSyncSize) will be scanned.
+// If there is no boundary in the tail, only the trailing zeros will be discarded.
+func recoverFile(fp string, window int64, framed bool) (discarded int64, err error) {
+
+	f, err := os.OpenFile(fp, os.O_RDWR, 0644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to open log file for recovery: %s", err.Error())
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat log file for recovery: %s", err.Error())
+	}
+	size := fi.Size()
+	if size == 0 {
+		return 0, nil
+	}
+
+	if window < recoverMinWindow {
+		window = recoverMinWindow
+	}
+	if window > size {
+		window = size
+	}
+	start := size - window
+	tail := make([]byte, window)
+	_, err = f.ReadAt(tail, start)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed to read log file for recovery: %s", err.Error())
+	}
+
+	boundary := lastBoundary
+	if framed {
+		boundary = lastFrameBoundary
+	}
+	keep := start + int64(boundary(tail))
+	if keep == size {
+		return 0, nil
+	}
+
+	err = f.Truncate(keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to truncate log file for recovery: %s", err.Error())
+	}
+	err = f.Sync()
+	if err != nil {
+		return 0, fmt.Errorf("failed to sync log file for recovery: %s", err.Error())
+	}
+	return size - keep, nil
+}
+
+// lastBoundary returns the end of the last complete line in p,
+// if there is no line, returns the end of the last non-zero byte.
+func lastBoundary(p []byte) int {
+	if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+		return i + 1
+	}
+	return lastNonZero(p)
+}
+
+func lastNonZero(p []byte) int {
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i] != 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// makeRecoveryMarker makes the record which will be written after recovery,
+// it starts with RFC3339 timestamp as normal log lines.
+func makeRecoveryMarker(discarded int64, t time.Time, framed bool) []byte {
+	p := []byte(fmt.Sprintf("%s logro: discarded %d bytes of torn tail in crash recovery\n",
+		t.UTC().Format(time.RFC3339Nano), discarded))
+	if framed {
+		return appendFrame(nil, frameMeta{flags: frameFlagTime, ts: t.UnixNano()}, p)
+	}
+	return p
+}
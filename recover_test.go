@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLastBoundary(t *testing.T) {
+	cases := []struct {
+		p   string
+		exp int
+	}{
+		{"", 0},
+		{"a\n", 2},
+		{"a\nb", 2},
+		{"a\nb\x00\x00", 2},
+		{"a\n\x00\x00", 2},
+		{"ab", 2},
+		{"ab\x00\x00", 2},
+		{"\x00\x00", 0},
+	}
+	for _, c := range cases {
+		if act := lastBoundary([]byte(c.p)); act != c.exp {
+			t.Fatalf("%q: mismatch boundary, exp: %d, act: %d", c.p, c.exp, act)
+		}
+	}
+}
+
+func TestStartupAppendRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "logro-test.log")
+	good := []byte("a\nb\n")
+	torn := append([]byte("c"), make([]byte, 4096)...)
+	err = ioutil.WriteFile(fp, append(good, torn...), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(&Config{OutputPath: fp, Developed: true, StartupMode: StartupAppend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.Stats().RecoveredBytes != int64(len(torn)) {
+		t.Fatal("mismatch recovered bytes", r.Stats().RecoveredBytes)
+	}
+
+	act, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(act, good) {
+		t.Fatal("complete records should be kept")
+	}
+	marker := act[len(good):]
+	if !bytes.HasSuffix(marker, []byte("logro: discarded 4097 bytes of torn tail in crash recovery\n")) {
+		t.Fatalf("mismatch recovery marker: %q", marker)
+	}
+	if r.fileSize != int64(len(act)) {
+		t.Fatal("mismatch file size")
+	}
+}
+
+func TestStartupAppendNoRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "logro-test.log")
+	good := []byte("a\nb\n")
+	err = ioutil.WriteFile(fp, good, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(&Config{OutputPath: fp, Developed: true, StartupMode: StartupAppend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.Stats().RecoveredBytes != 0 {
+		t.Fatal("should not discard anything")
+	}
+	if !isMatchFileSize(int64(len(good)), fp) {
+		t.Fatal("should not write marker")
+	}
+}
+
+func TestStartupAppendRecoveryFramed(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "logro-test.log")
+	good := appendFrame(appendFrame(nil, frameMeta{}, []byte("a\nb")), frameMeta{flags: frameFlagTime, ts: 1}, []byte("c"))
+	torn := appendFrame(nil, frameMeta{}, []byte("torn"))[:frameHeaderSize+1]
+	err = ioutil.WriteFile(fp, append(append([]byte(nil), good...), torn...), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(&Config{OutputPath: fp, Developed: true, StartupMode: StartupAppend, Framed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.Stats().RecoveredBytes != int64(len(torn)) {
+		t.Fatal("mismatch recovered bytes", r.Stats().RecoveredBytes)
+	}
+
+	f, err := os.Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fr := NewFrameReader(f)
+	act := readAllFrames(t, fr)
+	if len(act) != 3 || !bytes.Contains(act[2], []byte("crash recovery")) {
+		t.Fatal("recovery marker should be framed")
+	}
+	if fr.Skipped() != 0 {
+		t.Fatal("torn frame should be discarded")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"container/heap"
+	"time"
+)
+
+// reorderMaxWait is the max duration of waiting for a missing entry
+// when there is no new entry in ring buffer.
+// The missing one may be overwritten or still being written.
+const reorderMaxWait = 50 * time.Millisecond
+
+// reorderBuffer reorders entries by sequence number in sequenced mode,
+// it's only accessed by the reader of ring buffer.
+type reorderBuffer struct {
+	h      entryHeap
+	window int
+	// expected is the next expected sequence number.
+	expected uint64
+	// waitSince is the time of starting waiting for the expected one.
+	waitSince time.Time
+}
+
+func newReorderBuffer(window int) *reorderBuffer {
+	return &reorderBuffer{
+		h:        make(entryHeap, 0, window),
+		window:   window,
+		expected: 1,
+	}
+}
+
+// next returns the next entry in sequence order.
+//
+// Entries are taken from ring until the next expected one is found,
+// or the window is full, or there is no new entry for reorderMaxWait (the missing one is overwritten
+// or still being written). Late entries are returned as soon as they're found.
+func (b *reorderBuffer) next(ring ringBuffer) (*entry, bool) {
+
+	for {
+		if len(b.h) > 0 && (b.h[0].seq <= b.expected || len(b.h) >= b.window) {
+			return b.pop(), true
+		}
+		p, ok := ring.TryNext()
+		if !ok {
+			if len(b.h) == 0 {
+				return nil, false
+			}
+			if b.waitSince.IsZero() {
+				b.waitSince = time.Now()
+				return nil, false
+			}
+			if time.Since(b.waitSince) < reorderMaxWait {
+				return nil, false
+			}
+			return b.pop(), true
+		}
+		heap.Push(&b.h, (*entry)(p))
+		b.waitSince = time.Time{} // Writers are still working.
+	}
+}
+
+// flush returns the held entries in sequence order without waiting.
+func (b *reorderBuffer) flush() (*entry, bool) {
+	if len(b.h) == 0 {
+		return nil, false
+	}
+	return b.pop(), true
+}
+
+func (b *reorderBuffer) pop() *entry {
+	e := heap.Pop(&b.h).(*entry)
+	b.waitSince = time.Time{}
+	if e.seq >= b.expected {
+		b.expected = e.seq + 1
+	}
+	return e
+}
+
+// entryHeap is a min-heap of entries by sequence number.
+type entryHeap []*entry
+
+func (h entryHeap) Len() int { return len(h) }
+
+func (h entryHeap) Less(i, j int) bool { return h[i].seq < h[j].seq }
+
+func (h entryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *entryHeap) Push(x interface{}) {
+	*h = append(*h, x.(*entry))
+}
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// sliceRing is a ringBuffer of entries in slice.
+type sliceRing []*entry
+
+func (s *sliceRing) Set(data unsafe.Pointer) {
+	*s = append(*s, (*entry)(data))
+}
+
+func (s *sliceRing) TryNext() (unsafe.Pointer, bool) {
+	if len(*s) == 0 {
+		return nil, false
+	}
+	e := (*s)[0]
+	*s = (*s)[1:]
+	return unsafe.Pointer(e), true
+}
+
+func makeSliceRing(seqs ...uint64) *sliceRing {
+	s := new(sliceRing)
+	for _, seq := range seqs {
+		*s = append(*s, &entry{seq: seq})
+	}
+	return s
+}
+
+// readSeqs reads all entries, waiting for missing entries.
+func readSeqs(b *reorderBuffer, ring ringBuffer) (seqs []uint64) {
+	for {
+		e, ok := b.next(ring)
+		if !ok {
+			if len(b.h) == 0 {
+				return
+			}
+			time.Sleep(reorderMaxWait / 5)
+			continue
+		}
+		seqs = append(seqs, e.seq)
+	}
+}
+
+func TestReorderBuffer(t *testing.T) {
+	for i, c := range []struct {
+		window int
+		in     []uint64
+		exp    []uint64
+	}{
+		{8, []uint64{3, 1, 2, 5, 4}, []uint64{1, 2, 3, 4, 5}},
+		{8, []uint64{1, 3, 5, 4}, []uint64{1, 3, 4, 5}}, // Gap.
+		{2, []uint64{3, 4, 1, 2}, []uint64{3, 4, 1, 2}}, // Window is full.
+		{8, []uint64{2, 3}, []uint64{2, 3}},             // Missing the first one.
+	} {
+		b := newReorderBuffer(c.window)
+		act := readSeqs(b, makeSliceRing(c.in...))
+		if fmt.Sprint(act) != fmt.Sprint(c.exp) {
+			t.Fatal("mismatch order", i, act, c.exp)
+		}
+	}
+
+	// Missing entry is found before timeout.
+	b := newReorderBuffer(8)
+	ring := makeSliceRing(1, 3)
+	e, _ := b.next(ring)
+	if _, ok := b.next(ring); ok || e.seq != 1 {
+		t.Fatal("should wait for the missing one")
+	}
+	ring.Set(unsafe.Pointer(&entry{seq: 2}))
+	act := readSeqs(b, ring)
+	if fmt.Sprint(act) != fmt.Sprint([]uint64{2, 3}) {
+		t.Fatal("mismatch order", act)
+	}
+
+	// Late entry after timeout.
+	ring = makeSliceRing(5)
+	act = readSeqs(b, ring)
+	ring.Set(unsafe.Pointer(&entry{seq: 4}))
+	act = append(act, readSeqs(b, ring)...)
+	if fmt.Sprint(act) != fmt.Sprint([]uint64{5, 4}) {
+		t.Fatal("mismatch order", act)
+	}
+
+	// Flush without waiting.
+	ring = makeSliceRing(7)
+	if _, ok := b.next(ring); ok {
+		t.Fatal("should wait for the missing one")
+	}
+	e, ok := b.flush()
+	if !ok || e.seq != 7 {
+		t.Fatal("should flush")
+	}
+}
+
+func TestRotation_WriteSequenced(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "a.log")
+	r, err := New(&Config{
+		OutputPath: fp,
+		BufItem:    8192,
+		Shards:     4,
+		Sequenced:  true,
+		// Writers may be preempted between getting sequence number and writing,
+		// the window is large enough for all records.
+		ReorderWindow: 8192,
+		Framed:        true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	writers, cnt := 8, 500
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < cnt; j++ {
+				r.Write([]byte("a"))
+			}
+		}()
+	}
+	wg.Wait()
+	// Each record is 1 byte with frame header (time & seq).
+	exp := int64(writers * cnt * (1 + maxFrameHeaderSize))
+	for i := 0; i < 100 && r.Stats().Written != exp; i++ {
+		r.Sync()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	it, err := Open(fp, &ReadOptions{Framed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	n := uint64(0)
+	for it.Next() {
+		n++
+		if it.Record().Seq != n {
+			t.Fatal("mismatch sequence", it.Record().Seq, n)
+		}
+	}
+	if n != uint64(writers*cnt) {
+		t.Fatal("mismatch records", n)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"unsafe"
+
+	"github.com/templexxx/go-diodes"
+)
+
+// ringBuffer is the buffer between writers and writeLoop,
+// it overwrites data on writes in lieu of blocking.
+type ringBuffer interface {
+	// Set is called by many writers.
+	Set(data unsafe.Pointer)
+	// TryNext is called by one reader.
+	TryNext() (data unsafe.Pointer, ok bool)
+}
+
+func newRingBuffer(size, shards int, alerter diodes.Alerter) ringBuffer {
+	if shards <= 1 {
+		return diodes.NewManyToOne(size, alerter)
+	}
+	s := &shardedRing{rings: make([]*diodes.ManyToOne, shards)}
+	for i := range s.rings {
+		s.rings[i] = diodes.NewManyToOne(size, alerter)
+	}
+	return s
+}
+
+// shardedRing spreads writers across rings, reducing contention on one ring.
+//
+// Ordering:
+// Records in one ring keep Set order. The reader takes one record from each ring in turn,
+// so there is no order across rings.
+// A goroutine is mapped to a ring by its stack address,
+// which may change when the goroutine's stack grows or Write is called at different
+// stack depths, so even records of one goroutine may be reordered.
+type shardedRing struct {
+	rings []*diodes.ManyToOne
+	next  int // Next ring for reading.
+}
+
+func (s *shardedRing) Set(data unsafe.Pointer) {
+	s.rings[shardHint()%uint(len(s.rings))].Set(data)
+}
+
+func (s *shardedRing) TryNext() (data unsafe.Pointer, ok bool) {
+	for i := 0; i < len(s.rings); i++ {
+		d := s.rings[s.next]
+		s.next++
+		if s.next == len(s.rings) {
+			s.next = 0
+		}
+		data, ok = d.TryNext()
+		if ok {
+			return
+		}
+	}
+	return nil, false
+}
+
+// shardHint returns a hint of current goroutine.
+//
+// Goroutines have different stacks, so the address of a local variable
+// could tell goroutines apart without runtime help.
+func shardHint() uint {
+	var x byte
+	addr := uintptr(unsafe.Pointer(&x))
+	// Stacks are at least 2KB, drop the lower bits (position in stack),
+	// then mix the rest.
+	h := uint64(addr>>11) * 0x9e3779b97f4a7c15
+	return uint(h >> 32)
+}
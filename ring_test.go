@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestShardedRing(t *testing.T) {
+	writers, cnt := 8, 1000
+	s := newRingBuffer(writers*cnt, 4, nil).(*shardedRing)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for j := 0; j < cnt; j++ {
+				v := [2]int{w, j}
+				s.Set(unsafe.Pointer(&v))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got := make(map[[2]int]bool)
+	for {
+		p, ok := s.TryNext()
+		if !ok {
+			break
+		}
+		got[*(*[2]int)(p)] = true
+	}
+	if len(got) != writers*cnt {
+		t.Fatal("mismatch records", len(got))
+	}
+}
+
+func TestShardedRing_Order(t *testing.T) {
+	s := newRingBuffer(64, 4, nil).(*shardedRing)
+
+	// One goroutine at the same stack depth stays on one ring.
+	for i := 0; i < 32; i++ {
+		v := i
+		s.Set(unsafe.Pointer(&v))
+	}
+	for i := 0; i < 32; i++ {
+		p, ok := s.TryNext()
+		if !ok || *(*int)(p) != i {
+			t.Fatal("mismatch order", i)
+		}
+	}
+}
+
+func TestRotation_WriteShards(t *testing.T) {
+	fn := func(tr *testRotation) {
+		r := tr.r
+		cnt := int(r.cfg.MaxSize) / 2
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < cnt/4; j++ {
+					r.Write([]byte{'1'})
+				}
+			}()
+		}
+		wg.Wait()
+		r.Sync()
+		time.Sleep(10 * time.Millisecond)
+		if st := r.Stats(); st.Written != int64(cnt) || st.Dropped != 0 {
+			tr.Fatal("mismatch written", st)
+		}
+	}
+
+	cfg := *testConfig
+	testConfig.Shards = 4
+	defer func() { *testConfig = cfg }()
+	runTest(t, fn)
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Level is the level of a record routed by Router.
+//
+// It's the same as log/slog.Level,
+// zap's levels could be converted by multiplying by 4.
+type Level int
+
+// Levels of records.
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// Route is a Rotation in Router with its routing rule.
+type Route struct {
+	// Config is the Config of the Rotation.
+	Config *Config
+	// MinLevel is the minimum level of records written to the Rotation.
+	// Default: LevelInfo.
+	MinLevel Level
+	// Match is the extra predicate of records (with their levels)
+	// written to the Rotation, nil means matching all records at or above MinLevel.
+	// p must not be modified or retained.
+	Match func(level Level, p []byte) bool
+}
+
+type route struct {
+	minLevel Level
+	match    func(level Level, p []byte) bool
+	r        *Rotation
+}
+
+func (rt *route) isMatch(level Level, p []byte) bool {
+	if level < rt.minLevel {
+		return false
+	}
+	return rt.match == nil || rt.match(level, p)
+}
+
+// Router writes records to several Rotations by their levels.
+// A record is written to every matched Rotation, and dropped if no Rotation matches.
+//
+// e.g. Writing error+ records to app.error.log (30 backups),
+// and debug records to app.debug.log (2 backups):
+//
+//	NewRouter(
+//		Route{Config: &Config{OutputPath: "app.error.log", MaxBackups: 30}, MinLevel: LevelError},
+//		Route{Config: &Config{OutputPath: "app.debug.log", MaxBackups: 2}, MinLevel: LevelDebug,
+//			Match: func(level Level, p []byte) bool { return level < LevelInfo }},
+//	)
+//
+// Router implements io.Writer (writing copies at LevelInfo) for stdlib log,
+// see LevelWriter for other levels.
+type Router struct {
+	routes []*route
+}
+
+// NewRouter creates a Router with Rotations by routes.
+// Router owns the Rotations, they will be closed by Router.Close.
+func NewRouter(routes ...Route) (rt *Router, err error) {
+
+	if len(routes) == 0 {
+		return nil, errors.New("no route")
+	}
+
+	rt = new(Router)
+	for _, ro := range routes {
+		if ro.Config == nil {
+			rt.Close()
+			return nil, errors.New("nil route config")
+		}
+		r, err := New(ro.Config)
+		if err != nil {
+			rt.Close()
+			return nil, fmt.Errorf("failed to create route %s: %s", ro.Config.OutputPath, err.Error())
+		}
+		rt.routes = append(rt.routes, &route{
+			minLevel: ro.MinLevel,
+			match:    ro.Match,
+			r:        r,
+		})
+	}
+	return rt, nil
+}
+
+// Write writes a copy of p at LevelInfo,
+// p could be reused after Write (e.g. stdlib log puts it back to its pool).
+func (rt *Router) Write(p []byte) (written int, err error) {
+	return rt.writeCopy(LevelInfo, p)
+}
+
+// writeCopy copies p into an owned buffer, then writes it at level.
+func (rt *Router) writeCopy(level Level, p []byte) (written int, err error) {
+	b := GetBuffer()
+	b.Write(p)
+	return rt.WriteOwnedLevel(level, b)
+}
+
+// WriteLevel writes p to the matched Rotations.
+// p must not be modified after WriteLevel (see Rotation.Write).
+func (rt *Router) WriteLevel(level Level, p []byte) (written int, err error) {
+
+	for _, ro := range rt.routes {
+		if ro.isMatch(level, p) {
+			ro.r.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// WriteOwnedLevel is like WriteLevel, but takes the ownership of b (see Rotation.WriteOwned).
+// b is copied for every matched Rotation except the last one.
+func (rt *Router) WriteOwnedLevel(level Level, b OwnedBuffer) (written int, err error) {
+
+	p := b.Bytes()
+	var last *Rotation
+	for _, ro := range rt.routes {
+		if !ro.isMatch(level, p) {
+			continue
+		}
+		if last != nil {
+			c := GetBuffer()
+			c.Write(p)
+			last.WriteOwned(c)
+		}
+		last = ro.r
+	}
+	if last == nil {
+		b.Free()
+		return len(p), nil
+	}
+	return last.WriteOwned(b)
+}
+
+// LevelWriter returns an io.Writer writing copies at level like Router.Write,
+// e.g. log.New(rt.LevelWriter(LevelError), "", log.LstdFlags).
+func (rt *Router) LevelWriter(level Level) io.Writer {
+	return &levelWriter{rt: rt, level: level}
+}
+
+type levelWriter struct {
+	rt    *Router
+	level Level
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	return w.rt.writeCopy(w.level, p)
+}
+
+// Sync syncs all Rotations.
+func (rt *Router) Sync() (err error) {
+
+	for _, ro := range rt.routes {
+		if e := ro.r.Sync(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Close closes all Rotations, returns the first error.
+func (rt *Router) Close() (err error) {
+
+	for _, ro := range rt.routes {
+		if e := ro.r.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Stats returns the sum of statistics of all Rotations.
+func (rt *Router) Stats() (st Stats) {
+
+	for _, ro := range rt.routes {
+		st.add(ro.r.Stats())
+	}
+	return
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouter(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	errFP, debugFP, appFP := filepath.Join(dir, "app.error.log"), filepath.Join(dir, "app.debug.log"),
+		filepath.Join(dir, "app.log")
+	rt, err := NewRouter(
+		Route{Config: &Config{OutputPath: errFP, MaxBackups: 30}, MinLevel: LevelError},
+		Route{Config: &Config{OutputPath: debugFP, MaxBackups: 2}, MinLevel: LevelDebug,
+			Match: func(level Level, p []byte) bool { return level < LevelInfo }},
+		Route{Config: &Config{OutputPath: appFP}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	rt.WriteLevel(LevelDebug, []byte("debug\n"))
+	rt.Write([]byte("info\n"))
+	rt.WriteLevel(LevelWarn, []byte("warn\n"))
+	b := GetBuffer()
+	b.WriteString("error\n")
+	rt.WriteOwnedLevel(LevelError, b)
+	log.New(rt.LevelWriter(LevelError), "", 0).Print("fatal")
+
+	rt.Sync()
+	time.Sleep(10 * time.Millisecond)
+
+	for fp, exp := range map[string]string{
+		errFP:   "error\nfatal\n",
+		debugFP: "debug\n",
+		appFP:   "info\nwarn\nerror\nfatal\n",
+	} {
+		p, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, []byte(exp)) {
+			t.Fatal("mismatch records", fp, string(p))
+		}
+	}
+
+	st := rt.Stats()
+	if st.Written != int64(len("error\nfatal\ndebug\ninfo\nwarn\nerror\nfatal\n")) {
+		t.Fatal("mismatch stats", st)
+	}
+}
+
+func TestNewRouterFailed(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "a.log")
+	_, err = NewRouter(Route{Config: &Config{OutputPath: fp}}, Route{Config: &Config{OutputPath: fp}})
+	if err == nil {
+		t.Fatal("should fail with duplicated output path")
+	}
+	// The first Rotation should be closed (unlocked).
+	r, err := New(&Config{OutputPath: fp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+}
+
+// stdlib log reuses the buffer passed to Write.
+func TestRouter_StdLogConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "app.log")
+	rt, err := NewRouter(Route{Config: &Config{OutputPath: fp, BufItem: 8192}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	const goroutines, records = 8, 500
+	lg := log.New(rt.LevelWriter(LevelError), "", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				lg.Printf("record-%d-%d", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	rt.Sync()
+	time.Sleep(10 * time.Millisecond)
+	if rt.Stats().Dropped != 0 {
+		t.Skip("records dropped, too slow")
+	}
+
+	p, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := make(map[string]bool)
+	for i := 0; i < goroutines; i++ {
+		for j := 0; j < records; j++ {
+			exp[fmt.Sprintf("record-%d-%d", i, j)] = true
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(string(p), "\n"), "\n")
+	for _, line := range lines {
+		if !exp[line] {
+			t.Fatalf("unexpected record: %q", line)
+		}
+		delete(exp, line)
+	}
+	if len(exp) != 0 {
+		t.Fatal("records missing", len(exp))
+	}
+}
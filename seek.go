@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Seek makes the Iterator only return records whose time is in [from, to],
+// and restarts iteration.
+//
+// Record time is the framed timestamp in framed mode,
+// or the leading RFC3339 timestamp of the record.
+// Records without time following a record in range are returned too (e.g. multi-line records).
+//
+// Only the files may contain records in range are read (by backup timestamps),
+// and the start position in file is found by binary search
+// (except compressed backups), so records should be in time order.
+func (it *Iterator) Seek(from, to time.Time) {
+
+	it.closeFile()
+	it.seeking, it.started = true, false
+	it.from, it.to = from, to
+	it.files = nil
+
+	prev := int64(math.MinInt64)
+	for _, f := range it.allFiles {
+		// Backup timestamp is in second, and it's the max time of records in the file.
+		if f.ts >= from.Unix() && prev <= to.Unix() {
+			it.files = append(it.files, f)
+		}
+		prev = f.ts
+	}
+}
+
+// open opens the log file for iterating,
+// it starts at the first record in range when seeking.
+func (it *Iterator) open(fp string) error {
+
+	if !it.seeking || it.started || isCompressed(fp) {
+		return it.openFile(fp, 0)
+	}
+
+	f, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	active := fp == it.allFiles[len(it.allFiles)-1].fp
+	size, err := writtenSize(f, it.opts.DirectIO && active)
+	if err != nil {
+		return err
+	}
+	off, err := searchFile(f, size, it.opts.Framed, it.from)
+	if err != nil {
+		return fmt.Errorf("failed to search %s: %s", fp, err.Error())
+	}
+	return it.openFile(fp, off)
+}
+
+// inRange checks whether rec should be returned when seeking,
+// done is true if the following records are all out of range.
+func (it *Iterator) inRange(rec Record) (ok, done bool) {
+	t, has := recordTime(rec)
+	if !has {
+		return it.started, false
+	}
+	if t.After(it.to) {
+		return false, true
+	}
+	if t.Before(it.from) {
+		return false, false
+	}
+	it.started = true
+	return true, false
+}
+
+// recordTime returns the framed timestamp of rec,
+// or the leading RFC3339 timestamp of rec.Data.
+func recordTime(rec Record) (time.Time, bool) {
+	if !rec.Time.IsZero() {
+		return rec.Time, true
+	}
+	return parseLeadingTime(rec.Data)
+}
+
+// parseLeadingTime parses the RFC3339 timestamp at the beginning of p,
+// the timestamp should be followed by space or tab.
+func parseLeadingTime(p []byte) (time.Time, bool) {
+	if i := bytes.IndexAny(p, " \t"); i >= 0 {
+		p = p[:i]
+	}
+	if len(p) < len("2006-01-02T15:04:05Z") {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(p))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// searchLinearSize is the size of range will be scanned linearly in searchFile.
+const searchLinearSize = 64 * kb
+
+// searchFile returns an offset of record boundary in f,
+// all records before it are earlier than from.
+func searchFile(f *os.File, size int64, framed bool, from time.Time) (int64, error) {
+
+	lo, hi := int64(0), size
+	for hi-lo > searchLinearSize {
+		mid := lo + (hi-lo)/2
+		t, end, ok, err := firstTimeAfter(f, mid, hi, framed)
+		if err != nil {
+			return 0, err
+		}
+		if ok && t.Before(from) {
+			lo = end
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// firstTimeAfter finds the first record with time which starts in [off, limit),
+// returns its time and end offset.
+func firstTimeAfter(f *os.File, off, limit int64, framed bool) (t time.Time, end int64, ok bool, err error) {
+
+	start := off
+	if !framed && off > 0 {
+		// Start from the previous byte, the first token will be the rest of the line
+		// which off is in (an empty line if off is at the beginning of line).
+		start = off - 1
+	}
+
+	s := &recordSplitter{framed: framed, off: start}
+	sc := bufio.NewScanner(io.NewSectionReader(f, start, 1<<62))
+	sc.Buffer(make([]byte, 4*kb), maxRecordSize)
+	sc.Split(s.split)
+
+	first := true
+	for sc.Scan() {
+		if !framed && off > 0 && first {
+			first = false
+			continue
+		}
+		if s.recOff >= limit {
+			break
+		}
+		if t, ok = recordTime(s.record(sc.Bytes(), "")); ok {
+			return t, s.off, true, nil
+		}
+	}
+	return time.Time{}, 0, false, sc.Err()
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeTimedLogFiles makes n backups and the active log file,
+// each file has m records, one record per second from start.
+func makeTimedLogFiles(output string, n, m int, framed bool, start time.Time) (ts []time.Time, err error) {
+
+	for i := 0; i <= n; i++ {
+		var content []byte
+		var last time.Time
+		for j := 0; j < m; j++ {
+			last = start.Add(time.Duration(len(ts)) * time.Second)
+			ts = append(ts, last)
+			if framed {
+				content = appendFrame(content, frameMeta{flags: frameFlagTime, ts: last.UnixNano()},
+					[]byte(fmt.Sprintf("record-%d", len(ts))))
+			} else {
+				content = append(content, fmt.Sprintf("%s record-%d\nline2\n",
+					last.Format(time.RFC3339Nano), len(ts))...)
+			}
+		}
+
+		fp := output
+		if i < n {
+			fp, _ = makeBackupFP(output, false, last)
+		}
+		err = ioutil.WriteFile(fp, content, 0644)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func testIteratorSeek(t *testing.T, framed bool) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := 4096 // Large enough for binary search.
+	ts, err := makeTimedLogFiles(output, 3, m, framed, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := Open(output, &ReadOptions{Framed: framed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	ranges := [][2]int{{0, 0}, {0, 10}, {m - 1, m + 1}, {m + 100, 3*m + 7}, {4*m - 1, 4*m - 1}}
+	for _, rg := range ranges {
+		it.Seek(ts[rg[0]], ts[rg[1]])
+		var act []time.Time
+		for it.Next() {
+			rec := it.Record()
+			rt, ok := recordTime(rec)
+			if !ok {
+				if framed || string(rec.Data) != "line2" {
+					t.Fatalf("unexpected record: %q", rec.Data)
+				}
+				continue
+			}
+			act = append(act, rt)
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		exp := ts[rg[0] : rg[1]+1]
+		if len(act) != len(exp) {
+			t.Fatalf("range %v: mismatch records count, exp: %d, act: %d", rg, len(exp), len(act))
+		}
+		for i := range exp {
+			if !exp[i].Equal(act[i]) {
+				t.Fatalf("range %v: mismatch record time", rg)
+			}
+		}
+	}
+
+	it.Seek(ts[len(ts)-1].Add(time.Hour), ts[len(ts)-1].Add(2*time.Hour))
+	if it.Next() {
+		t.Fatal("should be empty")
+	}
+	if len(it.allFiles) != 4 {
+		t.Fatal("mismatch files")
+	}
+}
+
+func TestIteratorSeek(t *testing.T) {
+	testIteratorSeek(t, false)
+}
+
+func TestIteratorSeekFramed(t *testing.T) {
+	testIteratorSeek(t, true)
+}
+
+func TestSeekFiles(t *testing.T) {
+	it := &Iterator{allFiles: []iterFile{{"a", 10}, {"b", 20}, {"c", 30}}}
+	it.Seek(time.Unix(15, 0), time.Unix(25, 0))
+	if len(it.files) != 2 || it.files[0].fp != "b" || it.files[1].fp != "c" {
+		t.Fatal("mismatch files", it.files)
+	}
+	it.Seek(time.Unix(0, 0), time.Unix(5, 0))
+	if len(it.files) != 1 || it.files[0].fp != "a" {
+		t.Fatal("mismatch files", it.files)
+	}
+}
+
+func TestParseLeadingTime(t *testing.T) {
+	now := time.Now().Round(0)
+	for _, s := range []string{
+		now.Format(time.RFC3339Nano) + " msg",
+		now.Format(time.RFC3339Nano) + "\tmsg",
+		now.Format(time.RFC3339Nano),
+	} {
+		act, ok := parseLeadingTime([]byte(s))
+		if !ok || !act.Equal(now) {
+			t.Fatal("mismatch time", s)
+		}
+	}
+	for _, s := range []string{"", "msg", "2020-01-01 msg"} {
+		if _, ok := parseLeadingTime([]byte(s)); ok {
+			t.Fatal("should not have time", s)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/templexxx/logro/slogro
+
+go 1.21
+
+require github.com/templexxx/logro v0.0.0
+
+require (
+	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/templexxx/fnc v1.0.0 // indirect
+	github.com/templexxx/go-diodes v0.0.2 // indirect
+)
+
+replace github.com/templexxx/logro => ../
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+// Package slogro provides a log/slog Handler writing records to logro.Rotation.
+//
+// Records are encoded into buffers owned by logro (logro.GetBuffer),
+// so the reused encoding buffers in slog won't be written after reusing.
+package slogro
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/templexxx/logro"
+)
+
+// Options is the options of Handler.
+type Options struct {
+	slog.HandlerOptions
+	// Text makes Handler encode records as slog.TextHandler,
+	// default is JSON (slog.JSONHandler).
+	Text bool
+	// SyncLevel makes Handler sync Rotation (Rotation.Sync) after handling
+	// records at or above SyncLevel (e.g. slog.LevelError).
+	// Nil means Handler never syncs by itself.
+	SyncLevel slog.Leveler
+}
+
+// Handler is a slog.Handler writing records to logro.Rotation (or logro.Router).
+type Handler struct {
+	h         slog.Handler
+	w         *writer
+	syncLevel slog.Leveler
+}
+
+// New creates a Handler writing records to r.
+func New(r *logro.Rotation, opts *Options) *Handler {
+	return newHandler(&writer{r: r}, opts)
+}
+
+// NewRouter creates a Handler writing records to rt by their levels.
+//
+// Records are encoded one by one for passing levels to rt.
+func NewRouter(rt *logro.Router, opts *Options) *Handler {
+	return newHandler(&writer{rt: rt}, opts)
+}
+
+func newHandler(w *writer, opts *Options) *Handler {
+
+	if opts == nil {
+		opts = new(Options)
+	}
+
+	var h slog.Handler
+	if opts.Text {
+		h = slog.NewTextHandler(w, &opts.HandlerOptions)
+	} else {
+		h = slog.NewJSONHandler(w, &opts.HandlerOptions)
+	}
+	return &Handler{h: h, w: w, syncLevel: opts.SyncLevel}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, rec slog.Record) error {
+
+	var err error
+	if h.w.rt != nil {
+		h.w.mu.Lock()
+		h.w.level = logro.Level(rec.Level)
+		err = h.h.Handle(ctx, rec)
+		h.w.mu.Unlock()
+	} else {
+		err = h.h.Handle(ctx, rec)
+	}
+	if err != nil {
+		return err
+	}
+	if h.syncLevel != nil && rec.Level >= h.syncLevel.Level() {
+		return h.Sync()
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{h: h.h.WithAttrs(attrs), w: h.w, syncLevel: h.syncLevel}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{h: h.h.WithGroup(name), w: h.w, syncLevel: h.syncLevel}
+}
+
+// Sync flushes records to the log file by Rotation.Sync (or Router.Sync).
+func (h *Handler) Sync() error {
+	if h.w.rt != nil {
+		return h.w.rt.Sync()
+	}
+	return h.w.r.Sync()
+}
+
+// writer copies each encoded record (slog writes a record by one Write)
+// into a logro.Buffer, then passes its ownership to Rotation (or Router).
+type writer struct {
+	r *logro.Rotation
+
+	rt *logro.Router
+	// mu protects level, which is the level of the record being handled.
+	mu    sync.Mutex
+	level logro.Level
+}
+
+var _ io.Writer = (*writer)(nil)
+
+func (w *writer) Write(p []byte) (int, error) {
+	b := logro.GetBuffer()
+	b.Write(p)
+	if w.rt != nil {
+		return w.rt.WriteOwnedLevel(w.level, b)
+	}
+	return w.r.WriteOwned(b)
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package slogro
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/templexxx/logro"
+)
+
+func newTestRotation(t *testing.T) (r *logro.Rotation, dir string) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = logro.New(&logro.Config{
+		OutputPath: filepath.Join(dir, "a.log"),
+		BufItem:    8192,
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return r, dir
+}
+
+func readLines(t *testing.T, r *logro.Rotation, dir string) []string {
+	time.Sleep(20 * time.Millisecond)
+	r.Sync()
+	time.Sleep(20 * time.Millisecond)
+
+	p, err := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(p), "\n"), "\n")
+}
+
+func TestHandler_JSON(t *testing.T) {
+	r, dir := newTestRotation(t)
+	defer os.RemoveAll(dir)
+	defer r.Close()
+
+	l := slog.New(New(r, nil)).With("svc", "a")
+	writers, cnt := 4, 1000
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for j := 0; j < cnt; j++ {
+				l.Info("hello", "w", w, "seq", j, "pad", strings.Repeat("x", j%128))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := readLines(t, r, dir)
+	if len(lines) != writers*cnt {
+		t.Fatal("mismatch records", len(lines))
+	}
+	next := make([]int, writers)
+	for _, line := range lines {
+		var rec struct {
+			Msg string `json:"msg"`
+			Svc string `json:"svc"`
+			W   int    `json:"w"`
+			Seq int    `json:"seq"`
+			Pad string `json:"pad"`
+		}
+		err := json.Unmarshal([]byte(line), &rec)
+		if err != nil {
+			t.Fatal("corrupt record", line, err)
+		}
+		if rec.Msg != "hello" || rec.Svc != "a" || rec.Pad != strings.Repeat("x", rec.Seq%128) {
+			t.Fatal("mismatch record", line)
+		}
+		if rec.Seq != next[rec.W] {
+			t.Fatal("records of one writer should be in order", line)
+		}
+		next[rec.W]++
+	}
+}
+
+func TestHandler_Text(t *testing.T) {
+	r, dir := newTestRotation(t)
+	defer os.RemoveAll(dir)
+	defer r.Close()
+
+	h := New(r, &Options{Text: true, HandlerOptions: slog.HandlerOptions{Level: slog.LevelWarn}})
+	l := slog.New(h).WithGroup("g")
+	l.Info("ignored")
+	l.Warn("warn", "k", "v")
+	err := h.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := readLines(t, r, dir)
+	if len(lines) != 1 || !strings.Contains(lines[0], "msg=warn g.k=v") {
+		t.Fatal("mismatch records", lines)
+	}
+}
+
+func TestHandler_SyncLevel(t *testing.T) {
+	r, dir := newTestRotation(t)
+	defer os.RemoveAll(dir)
+	defer r.Close()
+
+	l := slog.New(New(r, &Options{SyncLevel: slog.LevelError}))
+	l.Error("boom")
+	time.Sleep(20 * time.Millisecond) // No Sync by caller.
+
+	p, err := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(p), `"msg":"boom"`) {
+		t.Fatal("error record should be synced", string(p))
+	}
+}
+
+func TestHandler_Router(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	errFP, appFP := filepath.Join(dir, "app.error.log"), filepath.Join(dir, "app.log")
+	rt, err := logro.NewRouter(
+		logro.Route{Config: &logro.Config{OutputPath: errFP}, MinLevel: logro.LevelError},
+		logro.Route{Config: &logro.Config{OutputPath: appFP}, MinLevel: logro.LevelDebug},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	h := NewRouter(rt, &Options{HandlerOptions: slog.HandlerOptions{Level: slog.LevelDebug}})
+	l := slog.New(h)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Debug("debug")
+				l.Error("error")
+			}
+		}()
+	}
+	wg.Wait()
+	h.Sync()
+	time.Sleep(20 * time.Millisecond)
+
+	for fp, exp := range map[string]int{errFP: 400, appFP: 800} {
+		p, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(string(p), "\n") != exp {
+			t.Fatal("mismatch records", fp)
+		}
+		if fp == errFP && strings.Contains(string(p), "debug") {
+			t.Fatal("debug record in error log")
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is the statistics of Rotation.
+type Stats struct {
+	// Written is the number of bytes written to log files.
+	Written int64
+	// Dropped is the number of records overwritten in buffer before writing to log files,
+	// it happens when writes are faster than logro could handle (see Config.BufItem).
+	Dropped int64
+	// Syncs is the number of background flushes (every PerSyncSize).
+	Syncs int64
+	// SyncTime is the total time spent in background flushes.
+	SyncTime time.Duration
+	// FlushErrors is the number of errors in background flushes
+	// (flushing, dropping cache, syncing & closing log files).
+	FlushErrors int64
+
+	// RecoveredBytes is the number of bytes discarded by crash recovery
+	// in StartupAppend mode.
+	RecoveredBytes int64
+	// Oversized is the number of records dropped because
+	// they're larger than MaxFramePayload in framed mode.
+	Oversized int64
+}
+
+// Stats returns the statistics of Rotation.
+func (r *Rotation) Stats() Stats {
+	return Stats{
+		Written:        atomic.LoadInt64(&r.stats.Written),
+		Dropped:        atomic.LoadInt64(&r.stats.Dropped),
+		Syncs:          atomic.LoadInt64(&r.stats.Syncs),
+		SyncTime:       time.Duration(atomic.LoadInt64((*int64)(&r.stats.SyncTime))),
+		FlushErrors:    atomic.LoadInt64(&r.stats.FlushErrors),
+		RecoveredBytes: atomic.LoadInt64(&r.stats.RecoveredBytes),
+		Oversized:      atomic.LoadInt64(&r.stats.Oversized),
+	}
+}
+
+func (s *Stats) add(o Stats) {
+	s.Written += o.Written
+	s.Dropped += o.Dropped
+	s.Syncs += o.Syncs
+	s.SyncTime += o.SyncTime
+	s.FlushErrors += o.FlushErrors
+	s.RecoveredBytes += o.RecoveredBytes
+	s.Oversized += o.Oversized
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotation_Stats(t *testing.T) {
+	fn := func(tr *testRotation) {
+		r := tr.r
+		for i := 0; i < int(r.cfg.MaxSize)*2; i++ {
+			r.Write([]byte{'1'})
+		}
+		st := r.Stats()
+		for i := 0; i < 100 && st.Syncs == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+			st = r.Stats()
+		}
+		if st.Syncs == 0 || st.SyncTime <= 0 {
+			tr.Fatal("should have background flushes", st)
+		}
+
+		for i := 0; i < 100 && st.Written+st.Dropped != r.cfg.MaxSize*2; i++ {
+			r.Sync()
+			time.Sleep(10 * time.Millisecond)
+			st = r.Stats()
+		}
+		if st.Written+st.Dropped != r.cfg.MaxSize*2 {
+			tr.Fatal("mismatch written", st.Written, st.Dropped)
+		}
+	}
+	runTest(t, fn)
+}
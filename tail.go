@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Tail returns the last n records of logro-managed log files of outputPath (from the oldest to the newest),
+// and the Checkpoint after them, following from it (FollowOptions.From) won't miss or repeat records.
+//
+// The active log file is read backwards from its end,
+// backups are read (from the newest) only if there are fewer than n records in it.
+func Tail(outputPath string, n int, opts *ReadOptions) (records []Record, cp Checkpoint, err error) {
+
+	var o ReadOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, cp, err
+		}
+	} else {
+		defer f.Close()
+		var fi os.FileInfo
+		fi, err = f.Stat()
+		if err != nil {
+			return nil, cp, err
+		}
+		cp.FileID = getFileID(fi)
+		cp.Offset, err = fileTailBoundary(f, o)
+		if err != nil {
+			return nil, cp, err
+		}
+		records, err = tailFile(f, outputPath, cp.Offset, n, o.Framed)
+		if err != nil {
+			return nil, cp, err
+		}
+	}
+
+	if len(records) >= n {
+		return records, cp, nil
+	}
+	bs, err := scanBackups(outputPath)
+	if err != nil {
+		return nil, cp, err
+	}
+	sorted := bs.sorted()
+	for i := len(sorted) - 1; i >= 0 && len(records) < n; i-- {
+		recs, err := tailBackup(sorted[i].fp, n-len(records), o.Framed, cp.FileID)
+		if err != nil {
+			if os.IsNotExist(err) { // Removed by rotation.
+				continue
+			}
+			return nil, cp, err
+		}
+		records = append(recs, records...)
+	}
+	return records, cp, nil
+}
+
+// tailBackup returns the last n records of backup fp.
+// The backup is skipped if it's the active log file (id) read by Tail (rotated after that).
+func tailBackup(fp string, n int, framed bool, id FileID) ([]Record, error) {
+
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if isCompressed(fp) {
+		return tailCompressed(f, fp, n, framed)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if getFileID(fi) == id {
+		return nil, nil
+	}
+	return tailFile(f, fp, fi.Size(), n, framed)
+}
+
+// tailFile returns the last n records before end (a record boundary) of f.
+//
+// It reads a window before end, doubling it until there are n records in it
+// or it reaches the beginning of f.
+func tailFile(f *os.File, fp string, end int64, n int, framed bool) ([]Record, error) {
+
+	if n <= 0 || end <= 0 {
+		return nil, nil
+	}
+
+	for window := int64(64 * kb); ; window *= 2 {
+		start := end - window
+		if start < 0 {
+			start = 0
+		}
+		from := start
+		if start > 0 && !framed {
+			from-- // Checking whether start is the beginning of a line.
+		}
+		p := make([]byte, end-from)
+		_, err := f.ReadAt(p, from)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read %s: %s", fp, err.Error())
+		}
+
+		s := &recordSplitter{framed: framed, off: from}
+		if start > 0 && !framed { // Skipping the partial line.
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				continue
+			}
+			p = p[i+1:]
+			s.off += int64(i + 1)
+		}
+		// In framed mode, the partial frame is skipped as corrupt bytes.
+		var recs []Record
+		for len(p) > 0 {
+			advance, token, _ := s.split(p, true)
+			if advance == 0 {
+				break
+			}
+			p = p[advance:]
+			if token != nil {
+				recs = append(recs, s.record(token, fp))
+			}
+		}
+		if len(recs) >= n || start == 0 {
+			if len(recs) > n {
+				recs = recs[len(recs)-n:]
+			}
+			return recs, nil
+		}
+	}
+}
+
+// tailCompressed returns the last n records of the compressed backup f,
+// which must be read from the beginning.
+func tailCompressed(f *os.File, fp string, n int, framed bool) ([]Record, error) {
+
+	rd, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %s", fp, err.Error())
+	}
+	s := &recordSplitter{framed: framed}
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 64*kb), maxRecordSize)
+	sc.Split(s.split)
+
+	last := make([]Record, 0, n)
+	i := 0
+	for sc.Scan() {
+		rec := s.record(sc.Bytes(), fp)
+		rec.Data = append([]byte(nil), rec.Data...)
+		if len(last) < n {
+			last = append(last, rec)
+		} else {
+			last[i%n] = rec
+		}
+		i++
+	}
+	if sc.Err() != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", fp, sc.Err().Error())
+	}
+	if len(last) < n {
+		return last, nil
+	}
+	return append(last[i%n:], last[:i%n]...), nil
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTail(t *testing.T) {
+	for _, framed := range []bool{false, true} {
+		testTail(t, framed)
+	}
+}
+
+func testTail(t *testing.T, framed bool) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "logro-test.log")
+	exp, err := makeTestLogFiles(output, 3, 10, framed, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ReadOptions{Framed: framed}
+	var cp Checkpoint
+	for _, n := range []int{0, 5, 15, 35, 100} { // Active log file, backups & the compressed one.
+		var recs []Record
+		recs, cp, err = Tail(output, n, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var act [][]byte
+		for _, rec := range recs {
+			act = append(act, rec.Data)
+		}
+		want := exp
+		if n < len(exp) {
+			want = exp[len(exp)-n:]
+		}
+		if !isMatchRecords(want, act) {
+			t.Fatalf("records mismatch, n: %d, framed: %t: %q", n, framed, act)
+		}
+	}
+
+	// Following from the Checkpoint.
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var next [][]byte
+	for i := 0; i < 3; i++ {
+		p := []byte(fmt.Sprintf("next-%d", i))
+		next = append(next, p)
+		if framed {
+			f.Write(appendFrame(nil, frameMeta{}, p))
+		} else {
+			f.Write(append(p, '\n'))
+		}
+	}
+	f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fl, err := Follow(ctx, output, &FollowOptions{ReadOptions: *opts, From: &cp, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+	if act := followN(t, fl, 3); !isMatchRecords(next, act) {
+		t.Fatalf("records mismatch: %q", act)
+	}
+}
+
+// Reading a larger window if there aren't enough records in it.
+func TestTailLarge(t *testing.T) {
+	for _, framed := range []bool{false, true} {
+		dir, err := ioutil.TempDir(os.TempDir(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		output := filepath.Join(dir, "logro-test.log")
+		var exp [][]byte
+		var content []byte
+		for i := 0; i < 20000; i++ {
+			p := []byte(fmt.Sprintf("record-%05d", i))
+			exp = append(exp, p)
+			if framed {
+				content = appendFrame(content, frameMeta{}, p)
+			} else {
+				content = append(append(content, p...), '\n')
+			}
+		}
+		err = ioutil.WriteFile(output, content, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, n := range []int{1, 9999, 20000} {
+			recs, cp, err := Tail(output, n, &ReadOptions{Framed: framed})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recs) != n || string(recs[0].Data) != string(exp[len(exp)-n]) ||
+				string(recs[n-1].Data) != string(exp[len(exp)-1]) {
+				t.Fatal("records mismatch", n, framed, len(recs))
+			}
+			if recs[0].Offset != int64((len(exp)-n)*(len(content)/len(exp))) {
+				t.Fatal("offset mismatch", n, recs[0].Offset)
+			}
+			if cp.Offset != int64(len(content)) {
+				t.Fatal("checkpoint mismatch", cp.Offset)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSize parses a human-readable size (e.g. "128MiB", "64KB", "4096") into bytes.
+//
+// Units are case-insensitive: B, K/KB/KiB, M/MB/MiB, G/GB/GiB, T/TB/TiB.
+// KB & KiB are both 1024 bytes (as the units of Config).
+func ParseSize(s string) (n int64, err error) {
+
+	s = strings.TrimSpace(s)
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("illegal size: %q", s)
+	}
+	n, err = strconv.ParseInt(s[:i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("illegal size: %q", s)
+	}
+
+	var shift uint
+	switch strings.ToLower(strings.TrimSpace(s[i:])) {
+	case "", "b":
+		shift = 0
+	case "k", "kb", "kib":
+		shift = 10
+	case "m", "mb", "mib":
+		shift = 20
+	case "g", "gb", "gib":
+		shift = 30
+	case "t", "tb", "tib":
+		shift = 40
+	default:
+		return 0, fmt.Errorf("illegal size unit: %q", s)
+	}
+	if n > (1<<63-1)>>shift {
+		return 0, fmt.Errorf("size overflow: %q", s)
+	}
+	return n << shift, nil
+}
+
+// sizeValue is a size field in config file,
+// it's a legacy integer in the field's unit, or a string with unit.
+type sizeValue struct {
+	n       int64
+	isBytes bool // n is in bytes (parsed from a string with unit).
+}
+
+func (v *sizeValue) UnmarshalJSON(data []byte) error {
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		v.isBytes = false
+		return json.Unmarshal(data, &v.n)
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+		v.n, v.isBytes = n, false // Legacy integer in a string.
+		return nil
+	}
+	n, err := ParseSize(s)
+	if err != nil {
+		return err
+	}
+	v.n, v.isBytes = n, true
+	return nil
+}
+
+// inUnit returns the size in unit.
+func (v *sizeValue) inUnit(unit int64) (int64, error) {
+	if !v.isBytes {
+		return v.n, nil
+	}
+	if v.n%unit != 0 {
+		return 0, fmt.Errorf("%d bytes is not a multiple of the unit (%d bytes)", v.n, unit)
+	}
+	return v.n / unit, nil
+}
+
+// durationValue is a duration field in config file,
+// it's a legacy integer (nanoseconds) or a string like "1h30m".
+type durationValue time.Duration
+
+func (v *durationValue) UnmarshalJSON(data []byte) error {
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return json.Unmarshal(data, (*int64)(v))
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("illegal duration: %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+// UnmarshalJSON unmarshals Config with human-readable sizes & durations, e.g.
+//
+//	{"max_size_mb": "128MiB", "per_write_size": "64KB", "lock_wait": "1m"}
+//
+// Legacy integers (in the units of fields) are still accepted.
+// A size string must be a multiple of its field's unit (bytes in Developed mode),
+// use the *_bytes fields for byte-exact sizes (e.g. "max_size_bytes": "1500KB").
+func (c *Config) UnmarshalJSON(data []byte) error {
+
+	type plain Config
+	aux := struct {
+		*plain
+		MaxSize      *sizeValue     `json:"max_size_mb"`
+		PerWriteSize *sizeValue     `json:"per_write_size"`
+		PerSyncSize  *sizeValue     `json:"per_sync_size"`
+		LockWait     *durationValue `json:"lock_wait"`
+
+		MaxSizeBytes      *sizeValue `json:"max_size_bytes"`
+		PerWriteSizeBytes *sizeValue `json:"per_write_size_bytes"`
+		PerSyncSizeBytes  *sizeValue `json:"per_sync_size_bytes"`
+	}{plain: (*plain)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	k, m := kb, mb
+	if c.Developed {
+		k, m = 1, 1
+	}
+	var err error
+	if aux.MaxSize != nil {
+		if c.MaxSize, err = aux.MaxSize.inUnit(m); err != nil {
+			return fmt.Errorf("illegal max_size_mb: %s", err.Error())
+		}
+	}
+	if aux.PerWriteSize != nil {
+		if c.PerWriteSize, err = aux.PerWriteSize.inUnit(k); err != nil {
+			return fmt.Errorf("illegal per_write_size: %s", err.Error())
+		}
+	}
+	if aux.PerSyncSize != nil {
+		if c.PerSyncSize, err = aux.PerSyncSize.inUnit(m); err != nil {
+			return fmt.Errorf("illegal per_sync_size: %s", err.Error())
+		}
+	}
+	if aux.LockWait != nil {
+		c.LockWait = time.Duration(*aux.LockWait)
+	}
+	for _, f := range []struct {
+		v   *sizeValue
+		dst *int64
+	}{
+		{aux.MaxSizeBytes, &c.MaxSizeBytes},
+		{aux.PerWriteSizeBytes, &c.PerWriteSizeBytes},
+		{aux.PerSyncSizeBytes, &c.PerSyncSizeBytes},
+	} {
+		if f.v != nil {
+			*f.dst, _ = f.v.inUnit(1)
+		}
+	}
+	return nil
+}
+
+// UnmarshalTOML unmarshals Config from a TOML table (decoded by the TOML library),
+// it accepts the same values as UnmarshalJSON.
+func (c *Config) UnmarshalTOML(v interface{}) error {
+
+	if _, ok := v.(map[string]interface{}); !ok {
+		return errors.New("config must be a TOML table")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal TOML config: %s", err.Error())
+	}
+	return c.UnmarshalJSON(data)
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+
+	cases := []struct {
+		s string
+		n int64
+	}{
+		{"0", 0},
+		{"4096", 4096},
+		{"12B", 12},
+		{"64KB", 64 * kb},
+		{"64kib", 64 * kb},
+		{"64K", 64 * kb},
+		{"128MiB", 128 * mb},
+		{" 128 MB ", 128 * mb},
+		{"2GiB", 2 << 30},
+		{"1TB", 1 << 40},
+	}
+	for _, c := range cases {
+		n, err := ParseSize(c.s)
+		if err != nil {
+			t.Fatal(c.s, err)
+		}
+		if n != c.n {
+			t.Fatalf("%s: mismatch, exp: %d, got: %d", c.s, c.n, n)
+		}
+	}
+
+	for _, s := range []string{"", "MB", "-1MB", "1.5MB", "1PB", "9999999999TB"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Fatalf("%q should be illegal", s)
+		}
+	}
+}
+
+func TestConfig_UnmarshalJSON(t *testing.T) {
+
+	var cfg Config
+	err := json.Unmarshal([]byte(`{"output_path": "a.log", "max_size_mb": "256MiB",
+		"per_write_size": "128KB", "per_sync_size": "1GB", "lock_wait": "1m30s", "max_backups": 3}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Config{OutputPath: "a.log", MaxSize: 256, PerWriteSize: 128, PerSyncSize: 1024,
+		LockWait: 90 * time.Second, MaxBackups: 3}
+	if cfg != exp {
+		t.Fatalf("mismatch, exp: %+v, got: %+v", exp, cfg)
+	}
+
+	// Legacy integers.
+	cfg = Config{}
+	err = json.Unmarshal([]byte(`{"max_size_mb": 256, "per_write_size": "128",
+		"per_sync_size": 2, "lock_wait": 1000}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = Config{MaxSize: 256, PerWriteSize: 128, PerSyncSize: 2, LockWait: time.Microsecond}
+	if cfg != exp {
+		t.Fatalf("mismatch, exp: %+v, got: %+v", exp, cfg)
+	}
+
+	// Bytes in Developed mode, whatever the order of fields.
+	cfg = Config{}
+	err = json.Unmarshal([]byte(`{"max_size_mb": "1KB", "per_write_size": "10B", "developed": true}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxSize != 1024 || cfg.PerWriteSize != 10 {
+		t.Fatal("mismatch")
+	}
+
+	// Byte-exact sizes.
+	cfg = Config{}
+	err = json.Unmarshal([]byte(`{"max_size_bytes": "1500KB", "per_write_size_bytes": 1000,
+		"per_sync_size_bytes": "3000"}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxSizeBytes != 1500*kb || cfg.PerWriteSizeBytes != 1000 || cfg.PerSyncSizeBytes != 3000 {
+		t.Fatal("mismatch")
+	}
+
+	for _, s := range []string{
+		`{"max_size_mb": "1500KB"}`, // Not a multiple of MB.
+		`{"per_write_size": "64XB"}`,
+		`{"lock_wait": "1y"}`,
+		`{"max_size_mb": true}`,
+	} {
+		if err = json.Unmarshal([]byte(s), new(Config)); err == nil {
+			t.Fatalf("%s should be illegal", s)
+		}
+	}
+}
+
+func TestConfig_UnmarshalTOML(t *testing.T) {
+
+	var cfg Config
+	err := cfg.UnmarshalTOML(map[string]interface{}{
+		"output_path":    "a.log",
+		"max_size_mb":    "64MiB",
+		"per_write_size": int64(32),
+		"lock_wait":      "2s",
+		"startup_mode":   "append",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Config{OutputPath: "a.log", MaxSize: 64, PerWriteSize: 32,
+		LockWait: 2 * time.Second, StartupMode: StartupAppend}
+	if cfg != exp {
+		t.Fatalf("mismatch, exp: %+v, got: %+v", exp, cfg)
+	}
+
+	if err = cfg.UnmarshalTOML("64MiB"); err == nil {
+		t.Fatal("should be illegal")
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// configUpdate is a request of UpdateConfig, applied by the writer of Rotation.
+type configUpdate struct {
+	cfg  *Config
+	errc chan error
+}
+
+// UpdateConfig updates the Config of a running Rotation without dropping buffered records.
+// It's applied between writes (by writeLoop or Manager's writer), and returns after that.
+// Records buffered before updating are written by the old Config.
+//
+// These fields could be changed:
+//
+//	OutputPath: records are written to the new log file (opened by StartupMode) after updating,
+//	            the old one is kept as it is (not moved to backups).
+//	            It can't be changed for Rotations managed by Manager.
+//	MaxBackups: the oldest backups are removed if there are too many.
+//	LocalTime, MaxSize, PerWriteSize, PerSyncSize (and their *Bytes), DisableAlignment,
+//	Preallocate (for files opened after updating), CachePolicy, LockWait, StartupMode, Lenient, Developed.
+//
+// BufItem, Shards, Sequenced, ReorderWindow, Framed & DirectIO can't be changed (after adjusting).
+//
+// cfg is validated like New, it returns *ConfigError if cfg is invalid.
+func (r *Rotation) UpdateConfig(cfg *Config) (err error) {
+
+	if r.isClosed() {
+		return ErrClosed
+	}
+
+	c, err := prepareConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if c.BufItem != r.cfg.BufItem || c.Shards != r.cfg.Shards ||
+		c.Sequenced != r.cfg.Sequenced || c.ReorderWindow != r.cfg.ReorderWindow ||
+		c.Framed != r.cfg.Framed || c.DirectIO != r.cfg.DirectIO {
+		return errors.New("BufItem, Shards, Sequenced, ReorderWindow, Framed & DirectIO can't be updated")
+	}
+	if r.mgr != nil && c.OutputPath != filepath.Join(r.mgr.cfg.Dir, r.name) {
+		return errors.New("OutputPath of Rotation managed by Manager can't be updated")
+	}
+
+	u := configUpdate{cfg: c, errc: make(chan error, 1)}
+	select {
+	case r.updates <- u:
+		r.notify()
+	case <-r.done:
+		return ErrClosed
+	}
+	select {
+	case err = <-u.errc:
+		return err
+	case <-r.done: // Closed before applying u.
+		select {
+		case err = <-u.errc:
+			return err
+		default:
+			return ErrClosed
+		}
+	}
+}
+
+// setMutable sets the fields of c which could be updated on a running Rotation by o.
+func (c *Config) setMutable(o *Config) {
+	c.OutputPath = o.OutputPath
+	c.MaxSize = o.MaxSize
+	c.MaxBackups = o.MaxBackups
+	c.LocalTime = o.LocalTime
+	c.LockWait = o.LockWait
+	c.StartupMode = o.StartupMode
+	c.PerWriteSize = o.PerWriteSize
+	c.PerSyncSize = o.PerSyncSize
+	c.MaxSizeBytes = o.MaxSizeBytes
+	c.PerWriteSizeBytes = o.PerWriteSizeBytes
+	c.PerSyncSizeBytes = o.PerSyncSizeBytes
+	c.DisableAlignment = o.DisableAlignment
+	c.Preallocate = o.Preallocate
+	c.CachePolicy = o.CachePolicy
+	c.Lenient = o.Lenient
+	c.Developed = o.Developed
+}
+
+// applyConfig applies the adjusted Config c, it's called by the writer of Rotation.
+func (r *Rotation) applyConfig(c *Config) (err error) {
+
+	r.writeBuffered()
+
+	if c.OutputPath != r.cfg.OutputPath {
+		err = r.switchOutput(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.PerWriteSize != r.cfg.PerWriteSize {
+		r.flushFile()
+		r.bufw = nil // Recreated by getBufIO with the new size.
+	}
+
+	r.cfg.setMutable(c)
+	r.trimBackups()
+
+	sc := *c
+	r.sendFlush(flushJob{cfg: &sc})
+	return nil
+}
+
+// switchOutput opens the log file at c.OutputPath for writing.
+// The current log file is kept if it fails.
+func (r *Rotation) switchOutput(c *Config) (err error) {
+
+	lock, err := lockLogFile(c.OutputPath, c.LockWait)
+	if err != nil {
+		return err
+	}
+	bs, err := listBackups(c.OutputPath, c.MaxBackups)
+	if err != nil {
+		unlockFile(lock)
+		return err
+	}
+
+	r.flushFile()
+	old := *r.cfg
+	oldF, oldLock, oldBackups, oldReserved := r.f, r.lock, r.backups, r.reserved
+	oldLength := int64(r.written)
+
+	r.cfg.setMutable(c)
+	r.f, r.backups = nil, bs
+	err = r.openExisting()
+	if err != nil {
+		r.cfg.setMutable(&old)
+		r.f, r.backups, r.reserved = oldF, oldBackups, oldReserved
+		unlockFile(lock)
+		return fmt.Errorf("failed to switch log file: %s", err.Error())
+	}
+	r.lock = lock
+	r.written = int(r.fileSize)
+	r.dirty = 0
+	r.dropDirectBuf()
+
+	releasePrealloc(oldF, oldReserved)
+	r.sendFlush(flushJob{f: oldF, isOld: true, length: oldLength, next: r.fileSize})
+	unlockFile(oldLock)
+	return nil
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func makeUpdateTestConfig(fp string) Config {
+	return Config{
+		OutputPath:   fp,
+		MaxSize:      1 << 20,
+		MaxBackups:   8,
+		BufItem:      1024,
+		PerWriteSize: 64,
+		PerSyncSize:  256,
+		Developed:    true,
+	}
+}
+
+// waitWritten waits until all records (n bytes) are written.
+func waitWritten(t *testing.T, r *Rotation, n int64) {
+	for i := 0; i < 200; i++ {
+		if err := r.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		st := r.Stats()
+		if st.Written+st.Dropped >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("records are not written", r.Stats(), n)
+}
+
+// waitBackups waits until there are at least n backups of fp.
+func waitBackups(t *testing.T, fp string, n int) {
+	for i := 0; i < 200; i++ {
+		bs, err := listBackups(fp, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bs.Len() >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("too few backups")
+}
+
+func TestRotation_UpdateConfigOutputPath(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	afp, bfp := filepath.Join(dir, "a.log"), filepath.Join(dir, "sub", "b.log")
+	cfg := makeUpdateTestConfig(afp)
+	r, err := New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var exp [2]bytes.Buffer
+	for i := 0; i < 100; i++ {
+		p := []byte(fmt.Sprintf("a-%d\n", i))
+		r.Write(p)
+		exp[0].Write(p)
+	}
+
+	ncfg := cfg
+	ncfg.OutputPath = bfp
+	err = r.UpdateConfig(&ncfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OutputPath != afp {
+		t.Fatal("cfg should be unchanged")
+	}
+
+	for i := 0; i < 100; i++ {
+		p := []byte(fmt.Sprintf("b-%d\n", i))
+		r.Write(p)
+		exp[1].Write(p)
+	}
+	waitWritten(t, r, int64(exp[0].Len()+exp[1].Len()))
+	if r.Stats().Dropped != 0 {
+		t.Skip("records dropped, too slow")
+	}
+
+	for i, fp := range []string{afp, bfp} {
+		act, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(act, exp[i].Bytes()) {
+			t.Fatalf("%s mismatch", fp)
+		}
+	}
+
+	// The lock is moved to the new log file.
+	f, err := lockFile(afp, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockFile(f)
+	_, err = lockFile(bfp, 0)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatal("the new log file should be locked", err)
+	}
+}
+
+func TestRotation_UpdateConfigRetention(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "a.log")
+	cfg := makeUpdateTestConfig(fp)
+	cfg.MaxSize = 64
+	r, err := New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	p := bytes.Repeat([]byte{'x'}, 100) // Larger than PerWriteSize, written directly.
+	for i := 0; i < 6; i++ {
+		r.Write(p)
+		waitBackups(t, fp, i+1)
+		time.Sleep(2 * time.Millisecond) // Backups are named by time in millisecond.
+	}
+
+	var bs *Backups
+
+	ncfg := cfg
+	ncfg.MaxBackups = 2
+	ncfg.MaxSize = 1 << 20
+	ncfg.PerWriteSize = 32
+	ncfg.PerSyncSize = 128
+	err = r.UpdateConfig(&ncfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err = listBackups(fp, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs.Len() != 2 {
+		t.Fatal("backups mismatch", bs.Len())
+	}
+
+	// No more rotation.
+	for i := 0; i < 4; i++ {
+		r.Write(p)
+	}
+	waitWritten(t, r, int64(len(p)*10))
+	bs, err = listBackups(fp, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs.Len() != 2 {
+		t.Fatal("backups mismatch", bs.Len())
+	}
+	if !isMatchFileSize(int64(len(p)*4), fp) {
+		t.Fatal("file size mismatch")
+	}
+}
+
+func TestRotation_UpdateConfigConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := makeUpdateTestConfig(filepath.Join(dir, "a.log"))
+	cfg.BufItem = 8192 // Enough for all records.
+	r, err := New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				r.Write([]byte("record\n"))
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		ncfg := cfg
+		ncfg.PerSyncSize = int64(128 * (i + 2))
+		ncfg.PerWriteSize = int64(16 * (i + 1))
+		ncfg.OutputPath = filepath.Join(dir, fmt.Sprintf("%d.log", i%3))
+		err = r.UpdateConfig(&ncfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	waitWritten(t, r, 4*1000*int64(len("record\n")))
+}
+
+func TestRotation_UpdateConfigIllegal(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := makeUpdateTestConfig(filepath.Join(dir, "a.log"))
+	r, err := New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fn := range []func(c *Config){
+		func(c *Config) { c.BufItem = 2048 },
+		func(c *Config) { c.Shards = 2 },
+		func(c *Config) { c.Sequenced = true },
+		func(c *Config) { c.Framed = true },
+		func(c *Config) { c.MaxBackups = -1 },
+		func(c *Config) { c.OutputPath = "" },
+	} {
+		ncfg := cfg
+		fn(&ncfg)
+		if r.UpdateConfig(&ncfg) == nil {
+			t.Fatalf("should be illegal: %+v", ncfg)
+		}
+	}
+
+	// Locked by another Rotation.
+	ocfg := makeUpdateTestConfig(filepath.Join(dir, "b.log"))
+	o, err := New(&ocfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	ncfg := cfg
+	ncfg.OutputPath = ocfg.OutputPath
+	if !errors.Is(r.UpdateConfig(&ncfg), ErrLocked) {
+		t.Fatal("should be locked")
+	}
+	r.Write([]byte("a\n"))
+	waitWritten(t, r, 2)
+	if !isMatchFileContent([]byte("a\n"), cfg.OutputPath) {
+		t.Fatal("should keep writing the old log file")
+	}
+
+	r.Close()
+	if r.UpdateConfig(&cfg) != ErrClosed {
+		t.Fatal("should be closed")
+	}
+}
+
+func TestManager_UpdateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := makeTestManager(t, dir)
+	defer m.Close()
+
+	r, err := m.Get("a.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := m.cfg.Template
+	cfg.OutputPath = filepath.Join(dir, "a.log")
+	cfg.MaxSize = 64
+	err = r.UpdateConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write(bytes.Repeat([]byte{'x'}, 100))
+	waitBackups(t, cfg.OutputPath, 1)
+
+	cfg.OutputPath = filepath.Join(dir, "b.log")
+	if r.UpdateConfig(&cfg) == nil {
+		t.Fatal("OutputPath of managed Rotation should not be updated")
+	}
+
+	r.Close()
+	if r.UpdateConfig(&cfg) != ErrClosed {
+		t.Fatal("should be closed")
+	}
+}
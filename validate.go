@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package logro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxMaxBackups is the max MaxBackups, more backups are meaningless
+// (log shipper should collect them) and slow down listing backups.
+const maxMaxBackups = 10000
+
+// accessWrite is W_OK of access(2).
+const accessWrite = 0x2
+
+// ConfigError is the error of invalid Config,
+// it has all problems found by Config.Validate.
+type ConfigError struct {
+	Errs []error
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns all problems, so errors.Is & errors.As could match any of them
+// (e.g. errors.Is(err, syscall.EACCES) for an unwritable log directory).
+func (e *ConfigError) Unwrap() []error {
+	return e.Errs
+}
+
+// Validate validates Config (before New adjusting it),
+// returns *ConfigError if there is any invalid value.
+// Zero values are valid, they mean defaults.
+func (c *Config) Validate() error {
+
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.OutputPath == "" {
+		add("empty log file path")
+	} else if err := checkOutputPath(c.OutputPath); err != nil {
+		errs = append(errs, err)
+	}
+
+	if !c.StartupMode.isValid() {
+		add("unknown startup mode: %s", c.StartupMode)
+	}
+	if !c.CachePolicy.isValid() {
+		add("unknown cache policy: %s", c.CachePolicy)
+	}
+
+	for _, f := range []struct {
+		name string
+		v    int64
+	}{
+		{"MaxSize", c.MaxSize},
+		{"MaxBackups", int64(c.MaxBackups)},
+		{"BufItem", int64(c.BufItem)},
+		{"Shards", int64(c.Shards)},
+		{"ReorderWindow", int64(c.ReorderWindow)},
+		{"PerWriteSize", c.PerWriteSize},
+		{"PerSyncSize", c.PerSyncSize},
+		{"MaxSizeBytes", c.MaxSizeBytes},
+		{"PerWriteSizeBytes", c.PerWriteSizeBytes},
+		{"PerSyncSizeBytes", c.PerSyncSizeBytes},
+	} {
+		if f.v < 0 {
+			add("negative %s: %d", f.name, f.v)
+		}
+	}
+	if c.DirectIO && !directIOSupported {
+		add("DirectIO is only supported on Linux")
+	}
+	if c.MaxBackups > maxMaxBackups {
+		add("too many MaxBackups: %d (max %d)", c.MaxBackups, maxMaxBackups)
+	}
+
+	for _, f := range []struct {
+		name string
+		n, b int64
+	}{
+		{"MaxSize", c.MaxSize, c.MaxSizeBytes},
+		{"PerWriteSize", c.PerWriteSize, c.PerWriteSizeBytes},
+		{"PerSyncSize", c.PerSyncSize, c.PerSyncSizeBytes},
+	} {
+		if f.n > 0 && f.b > 0 {
+			add("both %s & %sBytes are set", f.name, f.name)
+		}
+	}
+
+	// Check relations with defaults (not aligning).
+	maxSize := c.byteSize(c.MaxSizeBytes, c.MaxSize, mb, defaultMaxSize)
+	perWrite := c.byteSize(c.PerWriteSizeBytes, c.PerWriteSize, kb, defaultPerWriteSize)
+	perSync := c.byteSize(c.PerSyncSizeBytes, c.PerSyncSize, mb, defaultPerSyncSize)
+	if perWrite > maxSize {
+		add("PerWriteSize (%d bytes) > MaxSize (%d bytes)", perWrite, maxSize)
+	}
+	explicitSync := c.PerSyncSize > 0 || c.PerSyncSizeBytes > 0
+	if !c.Developed && explicitSync && perSync < 2*perWrite {
+		add("PerSyncSize (%d bytes) < 2 * PerWriteSize (%d bytes)", perSync, perWrite)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ConfigError{Errs: errs}
+}
+
+// checkOutputPath checks OutputPath is not a directory,
+// and its directory (or the nearest existing ancestor) is writable.
+func checkOutputPath(fp string) error {
+
+	fi, err := os.Stat(fp)
+	if err == nil && fi.IsDir() {
+		return fmt.Errorf("log file path is a directory: %s", fp)
+	}
+
+	dir := filepath.Dir(fp)
+	for {
+		fi, err = os.Stat(dir)
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat log directory: %w", err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("log directory not found: %s", dir)
+		}
+		dir = parent
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("log directory is not a directory: %s", dir)
+	}
+	if err = syscall.Access(dir, accessWrite); err != nil {
+		return fmt.Errorf("log directory is not writable: %s: %w", dir, err)
+	}
+	return nil
+}
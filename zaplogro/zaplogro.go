@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+// Package zaplogro provides zap integration for logro.Rotation.
+//
+// zapcore.AddSync(r) is unsafe, because zap puts the encoded buffer back to its pool
+// after Write returns, but Rotation writes it in background.
+// NewCore passes the ownership of encoded buffers to Rotation instead,
+// they are put back to zap's pool after writing.
+package zaplogro
+
+import (
+	"github.com/templexxx/logro"
+	"go.uber.org/zap/zapcore"
+)
+
+// Core is a zapcore.Core writing entries to logro.Rotation (or logro.Router).
+type Core struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	r   *logro.Rotation
+	rt  *logro.Router
+}
+
+// NewCore creates a Core writing entries encoded by enc to r.
+func NewCore(enc zapcore.Encoder, r *logro.Rotation, enab zapcore.LevelEnabler) zapcore.Core {
+	return &Core{
+		LevelEnabler: enab,
+		enc:          enc,
+		r:            r,
+	}
+}
+
+// NewRouterCore creates a Core writing entries encoded by enc to rt by their levels.
+func NewRouterCore(enc zapcore.Encoder, rt *logro.Router, enab zapcore.LevelEnabler) zapcore.Core {
+	return &Core{
+		LevelEnabler: enab,
+		enc:          enc,
+		rt:           rt,
+	}
+}
+
+// Level converts zap's level to logro.Level.
+func Level(l zapcore.Level) logro.Level {
+	return logro.Level(l) * 4
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &Core{LevelEnabler: c.LevelEnabler, enc: enc, r: c.r, rt: c.rt}
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+// The encoded buffer is owned by Rotation after writing.
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	if c.rt != nil {
+		_, err = c.rt.WriteOwnedLevel(Level(ent.Level), buf)
+	} else {
+		_, err = c.r.WriteOwned(buf)
+	}
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// Like zapcore.NewCore, sync before panicking or exiting.
+		return c.Sync()
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *Core) Sync() error {
+	if c.rt != nil {
+		return c.rt.Sync()
+	}
+	return c.r.Sync()
+}
+
+// WriteSyncer is a zapcore.WriteSyncer writing to logro.Rotation,
+// it copies p into a logro owned buffer before passing it to Rotation.
+//
+// It's for building cores by zapcore.NewCore (e.g. with zapcore.NewTee),
+// prefer NewCore which has no copying.
+type WriteSyncer struct {
+	r *logro.Rotation
+}
+
+// NewWriteSyncer creates a WriteSyncer writing to r.
+func NewWriteSyncer(r *logro.Rotation) *WriteSyncer {
+	return &WriteSyncer{r: r}
+}
+
+// Write implements io.Writer.
+func (w *WriteSyncer) Write(p []byte) (int, error) {
+	b := logro.GetBuffer()
+	b.Write(p)
+	return w.r.WriteOwned(b)
+}
+
+// Sync implements zapcore.WriteSyncer.
+func (w *WriteSyncer) Sync() error {
+	return w.r.Sync()
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2019. Temple3x (temple3x@gmail.com)
+ *
+ * Use of this source code is governed by the MIT License
+ * that can be found in the LICENSE file.
+ */
+
+package zaplogro
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/templexxx/logro"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestRotation(t *testing.T) (r *logro.Rotation, dir string) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = logro.New(&logro.Config{
+		OutputPath: filepath.Join(dir, "a.log"),
+		BufItem:    8192,
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return r, dir
+}
+
+func newTestEncoder() zapcore.Encoder {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.TimeKey = ""
+	return zapcore.NewJSONEncoder(cfg)
+}
+
+// testConcurrent writes records by many goroutines, and checks them in log file.
+// Records will be corrupted if zap's buffers are reused before writing.
+func testConcurrent(t *testing.T, core func(r *logro.Rotation) zapcore.Core) {
+	r, dir := newTestRotation(t)
+	defer os.RemoveAll(dir)
+	defer r.Close()
+
+	l := zap.New(core(r)).With(zap.String("svc", "a"))
+	writers, cnt := 4, 1000
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for j := 0; j < cnt; j++ {
+				l.Info("hello", zap.Int("w", w), zap.Int("seq", j),
+					zap.String("pad", strings.Repeat(string(rune('a'+w)), j%128)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	l.Sync()
+	time.Sleep(20 * time.Millisecond)
+
+	p, err := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(p), "\n"), "\n")
+	if len(lines) != writers*cnt {
+		t.Fatal("mismatch records", len(lines))
+	}
+	next := make([]int, writers)
+	for _, line := range lines {
+		var rec struct {
+			Msg string `json:"msg"`
+			Svc string `json:"svc"`
+			W   int    `json:"w"`
+			Seq int    `json:"seq"`
+			Pad string `json:"pad"`
+		}
+		err := json.Unmarshal([]byte(line), &rec)
+		if err != nil {
+			t.Fatal("corrupt record", line, err)
+		}
+		if rec.Msg != "hello" || rec.Svc != "a" || rec.Pad != strings.Repeat(string(rune('a'+rec.W)), rec.Seq%128) {
+			t.Fatal("mismatch record", line)
+		}
+		if rec.Seq != next[rec.W] {
+			t.Fatal("records of one writer should be in order", line)
+		}
+		next[rec.W]++
+	}
+}
+
+func TestCore(t *testing.T) {
+	testConcurrent(t, func(r *logro.Rotation) zapcore.Core {
+		return NewCore(newTestEncoder(), r, zapcore.InfoLevel)
+	})
+}
+
+func TestWriteSyncer(t *testing.T) {
+	testConcurrent(t, func(r *logro.Rotation) zapcore.Core {
+		return zapcore.NewCore(newTestEncoder(), NewWriteSyncer(r), zapcore.InfoLevel)
+	})
+}
+
+func TestCore_Level(t *testing.T) {
+	r, dir := newTestRotation(t)
+	defer os.RemoveAll(dir)
+	defer r.Close()
+
+	l := zap.New(NewCore(newTestEncoder(), r, zapcore.WarnLevel))
+	l.Info("ignored")
+	l.Warn("warn")
+	l.Sync()
+	time.Sleep(20 * time.Millisecond)
+
+	p, err := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(p), "ignored") || !strings.Contains(string(p), `"msg":"warn"`) {
+		t.Fatal("mismatch records", string(p))
+	}
+}
+
+func TestRouterCore(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	errFP, appFP := filepath.Join(dir, "app.error.log"), filepath.Join(dir, "app.log")
+	rt, err := logro.NewRouter(
+		logro.Route{Config: &logro.Config{OutputPath: errFP}, MinLevel: logro.LevelError},
+		logro.Route{Config: &logro.Config{OutputPath: appFP}, MinLevel: logro.LevelDebug},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	l := zap.New(NewRouterCore(newTestEncoder(), rt, zapcore.DebugLevel))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Debug("debug")
+				l.Error("error")
+			}
+		}()
+	}
+	wg.Wait()
+	l.Sync()
+	time.Sleep(20 * time.Millisecond)
+
+	for fp, exp := range map[string]int{errFP: 400, appFP: 800} {
+		p, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(string(p), "\n") != exp {
+			t.Fatal("mismatch records", fp)
+		}
+		if fp == errFP && strings.Contains(string(p), "debug") {
+			t.Fatal("debug record in error log")
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for zl, l := range map[zapcore.Level]logro.Level{
+		zapcore.DebugLevel: logro.LevelDebug,
+		zapcore.InfoLevel:  logro.LevelInfo,
+		zapcore.WarnLevel:  logro.LevelWarn,
+		zapcore.ErrorLevel: logro.LevelError,
+	} {
+		if Level(zl) != l {
+			t.Fatal("mismatch level", zl)
+		}
+	}
+}